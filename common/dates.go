@@ -0,0 +1,24 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+var monthsGenitive = [...]string{
+	"января", "февраля", "марта", "апреля", "мая", "июня",
+	"июля", "августа", "сентября", "октября", "ноября", "декабря",
+}
+
+// FormatDate возвращает дату в виде «2 сентября»
+func FormatDate(t time.Time) string {
+	return fmt.Sprintf("%d %s", t.Day(), monthsGenitive[t.Month()-1])
+}
+
+// ParseDate разбирает дату из MySQL (DATE или DATETIME) в указанной зоне
+func ParseDate(value string, loc *time.Location) (time.Time, error) {
+	if len(value) >= len("2006-01-02") {
+		value = value[:len("2006-01-02")]
+	}
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
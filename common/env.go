@@ -3,6 +3,7 @@ package common
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 func GetEnvString(key string, defaultValue string) string {
@@ -20,3 +21,23 @@ func GetEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func GetEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// GetEnvList разбирает список через запятую, пропуская пустые элементы
+func GetEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
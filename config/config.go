@@ -1,7 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+	"unicode/utf8"
 	"yandex-export/common"
 	"yandex-export/entity"
 
@@ -12,7 +18,7 @@ var CompanyName = "Школа танцев «Без правил»"
 var FirstVisitPrice int
 var VisitPrice int
 
-var Categories entity.Categories = entity.Categories{[]entity.Category{
+var Categories entity.Categories = entity.Categories{Category: []entity.Category{
 	{ID: 1, Name: "Танцевальные классы (разовое посещение)"},
 	{ID: 2, Name: "Абонементы"},
 }}
@@ -25,15 +31,146 @@ type DBConfig struct {
 	DBName   string
 }
 
+// SeasonTag — тег картинок, активный ежегодно с From по To включительно.
+// Даты задаются как «ММ-ДД»; если To раньше From, сезон переходит через Новый год.
+type SeasonTag struct {
+	Tag  string
+	From string
+	To   string
+}
+
+// Covers сообщает, попадает ли день в сезон
+func (s SeasonTag) Covers(t time.Time) bool {
+	day := t.Format("01-02")
+	if s.From <= s.To {
+		return day >= s.From && day <= s.To
+	}
+	return day >= s.From || day <= s.To
+}
+
+func parseSeasonTag(rule string) (SeasonTag, error) {
+	tag, dates, ok := strings.Cut(rule, ":")
+	if !ok {
+		return SeasonTag{}, fmt.Errorf("expected tag:MM-DD..MM-DD")
+	}
+	from, to, ok := strings.Cut(dates, "..")
+	if !ok {
+		return SeasonTag{}, fmt.Errorf("expected tag:MM-DD..MM-DD")
+	}
+	for _, date := range []string{from, to} {
+		if _, err := time.Parse("01-02", date); err != nil {
+			return SeasonTag{}, fmt.Errorf("invalid date %q: %w", date, err)
+		}
+	}
+	return SeasonTag{Tag: strings.TrimSpace(tag), From: from, To: to}, nil
+}
+
 var DatabaseConfig *DBConfig
 var Port string
+var AdminToken string
+var AdminPath string
 var YandexPath string
+var GooglePath string
+var XmlCompact bool
+var VkPath string
+var VkCategories map[int]entity.Category
+var AvitoPath string
+var JsonPath string
+var CsvPath string
+var CsvDelimiter rune
+var JsonLdPath string
+var ICalPath string
+var BusinessPath string
+var BusinessSections map[string]string
+var AvitoAddress string
+var AvitoContactPhone string
+var AvitoManagerName string
+var AvitoServiceType string
+var AvitoServiceSubtype string
+var StudiosQuery string
+var ShopURL string
+var ShopName string
+var ShopPlatform string
+var ShopVersion string
+var ShopEmail string
 var ClassDefaultPicture string
 var PassDefaultPicture string
 var ClassDefaultLink string
 var PassDefaultLink string
 var ImageDir string
 var ImagePath string
+var ServeImages bool
+var PublicURL string
+var ImageServePath string
+var ImageURLVersion bool
+var ImageManifest string
+var ImageTags []string
+var ImageSeasonTags []SeasonTag
+var MaxPictures int
+var MinImageWidth int
+var MinImageHeight int
+var MaxImageSize int64
+var ImageDiagnosticsPath string
+var ImageResize bool
+var ImageResizeWidth int
+var ImageResizeHeight int
+var ImageResizeMode string
+var ImageCacheDir string
+var ImageCachePath string
+var ClassSessionsTable string
+var Location *time.Location
+var ClosuresFile string
+var ClosuresTable string
+var ClosureLookaheadDays int
+var ClosurePolicy string
+var UpcomingDays int
+var NewGroupsCategoryID int
+var TeachersEnabled bool
+var TeachersQuery string
+var AvailabilitySource string
+var AvailabilityPolicy string
+var CapacityQuery string
+var PausedQuery string
+
+const (
+	// ClosurePolicyHide — не выгружать классы, у которых нет занятий в ближайшие дни
+	ClosurePolicyHide = "hide"
+	// ClosurePolicyAnnotate — выгружать такие классы с пометкой о перерыве
+	ClosurePolicyAnnotate = "annotate"
+)
+
+const (
+	// AvailabilitySourceCapacity — группа заполнена, если записанных не меньше вместимости
+	AvailabilitySourceCapacity = "capacity"
+	// AvailabilitySourcePaused — группа недоступна, если в CRM стоит пауза
+	AvailabilitySourcePaused = "paused"
+
+	// AvailabilityPolicyMark — выгружать недоступные классы с available="false"
+	AvailabilityPolicyMark = "mark"
+	// AvailabilityPolicyHide — не выгружать недоступные классы
+	AvailabilityPolicyHide = "hide"
+)
+
+// parseVkCategory разбирает правило «наш ID:ID категории VK[:название]»
+func parseVkCategory(rule string) (int, entity.Category, error) {
+	parts := strings.SplitN(rule, ":", 3)
+	if len(parts) < 2 {
+		return 0, entity.Category{}, fmt.Errorf("expected id:vk_id[:name]")
+	}
+	ourID, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, entity.Category{}, fmt.Errorf("invalid id %q: %w", parts[0], err)
+	}
+	vkID, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, entity.Category{}, fmt.Errorf("invalid vk id %q: %w", parts[1], err)
+	}
+	category := entity.Category{ID: vkID}
+	if len(parts) == 3 {
+		category.Name = strings.TrimSpace(parts[2])
+	}
+	return ourID, category, nil
+}
 
 func init() {
 	// Попробуем загрузить .env из текущей папки.
@@ -54,11 +191,172 @@ func init() {
 		DBName:   common.GetEnvString("DB_NAME", "root"),
 	}
 	Port = common.GetEnvString("PORT", "9999")
+	// Админские ручки работают, только если задан ADMIN_TOKEN
+	AdminToken = common.GetEnvString("ADMIN_TOKEN", "")
+	AdminPath = "/" + strings.Trim(common.GetEnvString("ADMIN_PATH", "/admin"), "/")
 	YandexPath = common.GetEnvString("YANDEX_PATH", "/yandex.yml")
+	// YML без отступов меньше и быстрее; в запросе можно переопределить через ?compact=
+	XmlCompact = common.GetEnvBool("XML_COMPACT", false)
+	// Пустой путь отключает соответствующую выгрузку
+	GooglePath = common.GetEnvString("GOOGLE_PATH", "/google.xml")
+	// Заголовок магазина в YML
+	ShopURL = common.GetEnvString("SHOP_URL", "https://bezpravil.net")
+	ShopName = common.GetEnvString("SHOP_NAME", CompanyName)
+	ShopPlatform = common.GetEnvString("SHOP_PLATFORM", "yandex-export")
+	ShopVersion = common.GetEnvString("SHOP_VERSION", "")
+	ShopEmail = common.GetEnvString("SHOP_EMAIL", "")
+	VkPath = common.GetEnvString("VK_PATH", "/vk.yml")
+	// Соответствие наших категорий категориям ВКонтакте:
+	// VK_CATEGORIES=1:1605:Спорт и фитнес,2:1605 — наш ID, ID категории VK и (необязательно) название
+	VkCategories = make(map[int]entity.Category)
+	for _, rule := range common.GetEnvList("VK_CATEGORIES") {
+		ourID, category, err := parseVkCategory(rule)
+		if err != nil {
+			log.Printf("Пропускаем правило VK_CATEGORIES %q: %v", rule, err)
+			continue
+		}
+		VkCategories[ourID] = category
+	}
+	// Тот же каталог для сайта и внутренних инструментов
+	JsonPath = common.GetEnvString("JSON_PATH", "/catalog.json")
+	CsvPath = common.GetEnvString("CSV_PATH", "/catalog.csv")
+	// Excel с русской локалью разделяет колонки точкой с запятой; для запятой — CSV_DELIMITER=,
+	CsvDelimiter = ';'
+	if delimiter := common.GetEnvString("CSV_DELIMITER", ";"); delimiter == `\t` {
+		CsvDelimiter = '\t'
+	} else if r, size := utf8.DecodeRuneInString(delimiter); size == len(delimiter) && r != utf8.RuneError &&
+		!strings.ContainsRune("\"\r\n", r) {
+		CsvDelimiter = r
+	} else {
+		log.Printf("Неверный CSV_DELIMITER %q: ожидается один символ, используем «;»", delimiter)
+	}
+	// Разметка schema.org для сайта; ?snippet=1 отдаёт готовый <script>
+	JsonLdPath = common.GetEnvString("JSONLD_PATH", "/catalog.jsonld")
+	// Календарь занятий для подписки; ?studio= и ?style= фильтруют классы
+	ICalPath = common.GetEnvString("ICAL_PATH", "/classes.ics")
+	// Прайс-лист для Яндекс Бизнеса. Разделы задаются правилами
+	// BUSINESS_SECTIONS=style:hip-hop=Хип-хоп,studio:2=Филиал на Ленина,category:1=Танцы,kind:pass=Абонементы;
+	// без подходящего правила раздел — название категории
+	BusinessPath = common.GetEnvString("BUSINESS_PATH", "/business.yml")
+	BusinessSections = make(map[string]string)
+	for _, rule := range common.GetEnvList("BUSINESS_SECTIONS") {
+		key, section, ok := strings.Cut(rule, "=")
+		if !ok || strings.TrimSpace(section) == "" {
+			log.Printf("Пропускаем правило BUSINESS_SECTIONS %q: ожидается ключ=раздел", rule)
+			continue
+		}
+		BusinessSections[strings.TrimSpace(key)] = strings.TrimSpace(section)
+	}
+	// Автозагрузка Авито. Адрес объявления — адрес студии класса;
+	// AVITO_ADDRESS используется для абонементов и студий без адреса
+	AvitoPath = common.GetEnvString("AVITO_PATH", "/avito.xml")
+	AvitoAddress = common.GetEnvString("AVITO_ADDRESS", "")
+	AvitoContactPhone = common.GetEnvString("AVITO_CONTACT_PHONE", "")
+	AvitoManagerName = common.GetEnvString("AVITO_MANAGER_NAME", "")
+	AvitoServiceType = common.GetEnvString("AVITO_SERVICE_TYPE", "Обучение, курсы")
+	AvitoServiceSubtype = common.GetEnvString("AVITO_SERVICE_SUBTYPE", "Танцы")
+	// Запрос должен вернуть колонки id, title, address
+	StudiosQuery = common.GetEnvString("STUDIOS_QUERY", `
+		SELECT s.id, s.studio_title, s.address
+		FROM studios AS s`)
 	ClassDefaultPicture = common.GetEnvString("CLASS_DEFAULT_PICTURE", "https://bezpravil.net/img/logo.png")
 	ClassDefaultLink = common.GetEnvString("CLASS_DEFAULT_LINK", "https://bezpravil.net")
 	PassDefaultPicture = common.GetEnvString("PASS_DEFAULT_PICTURE", "https://bezpravil.net/img/logo.png")
 	PassDefaultLink = common.GetEnvString("PASS_DEFAULT_LINK", "https://bezpravil.net")
 	ImageDir = common.GetEnvString("IMAGE_DIR", "images")
-	ImagePath = common.GetEnvString("IMAGE_PATH", "https://bezpravil.net/img")
+	// Добавлять к адресам картинок ?v=<хеш содержимого>, чтобы замена файла меняла адрес
+	ImageURLVersion = common.GetEnvBool("IMAGE_URL_VERSION", false)
+	// Файл в каталоге с картинками, задающий их веса, даты показа и привязки
+	ImageManifest = common.GetEnvString("IMAGE_MANIFEST", "images.json")
+	// Подкаталоги с этими именами (например, 1/summer/) работают как теги:
+	// картинки из них показываются, только пока тег активен.
+	// IMAGE_TAGS=promo,summer — всегда активные теги;
+	// IMAGE_SEASON_TAGS=summer:06-01..08-31,winter:12-01..02-28 — активные в указанные даты
+	ImageTags = common.GetEnvList("IMAGE_TAGS")
+	for _, rule := range common.GetEnvList("IMAGE_SEASON_TAGS") {
+		season, err := parseSeasonTag(rule)
+		if err != nil {
+			log.Printf("Пропускаем правило IMAGE_SEASON_TAGS %q: %v", rule, err)
+			continue
+		}
+		ImageSeasonTags = append(ImageSeasonTags, season)
+	}
+	// Если SERVE_IMAGES включён, картинки из IMAGE_DIR и их уменьшенные копии
+	// раздаёт сам сервис по адресу PUBLIC_URL + IMAGE_SERVE_PATH
+	ServeImages = common.GetEnvBool("SERVE_IMAGES", false)
+	PublicURL = common.GetEnvString("PUBLIC_URL", "http://localhost:"+Port)
+	ImageServePath = "/" + strings.Trim(common.GetEnvString("IMAGE_SERVE_PATH", "/img/"), "/") + "/"
+	if ServeImages {
+		ImagePath = common.GetEnvString("IMAGE_PATH", strings.TrimRight(PublicURL, "/")+strings.TrimRight(ImageServePath, "/"))
+	} else {
+		ImagePath = common.GetEnvString("IMAGE_PATH", "https://bezpravil.net/img")
+	}
+	// Яндекс принимает до 10 картинок на предложение
+	MaxPictures = min(max(common.GetEnvInt("MAX_PICTURES", 3), 1), 10)
+	// Картинки, не прошедшие проверку, не попадают в выгрузку. Список отброшенных
+	// показывается на IMAGE_DIAGNOSTICS_PATH (по умолчанию выключено, нужен ADMIN_TOKEN)
+	MinImageWidth = common.GetEnvInt("IMAGE_MIN_WIDTH", 250)
+	MinImageHeight = common.GetEnvInt("IMAGE_MIN_HEIGHT", 250)
+	MaxImageSize = int64(common.GetEnvInt("IMAGE_MAX_SIZE", 10*1024*1024))
+	ImageDiagnosticsPath = common.GetEnvString("IMAGE_DIAGNOSTICS_PATH", "")
+
+	// Уменьшенные копии картинок (JPEG) складываются в IMAGE_CACHE_DIR
+	// и публикуются по адресу IMAGE_CACHE_PATH вместо оригиналов
+	ImageResize = common.GetEnvBool("IMAGE_RESIZE", false)
+	ImageResizeWidth = common.GetEnvInt("IMAGE_RESIZE_WIDTH", 600)
+	ImageResizeHeight = common.GetEnvInt("IMAGE_RESIZE_HEIGHT", 600)
+	ImageResizeMode = common.GetEnvString("IMAGE_RESIZE_MODE", "crop")
+	ImageCacheDir = common.GetEnvString("IMAGE_CACHE_DIR", "image_cache")
+	ImageCachePath = common.GetEnvString("IMAGE_CACHE_PATH", strings.TrimRight(ImagePath, "/")+"/cache")
+	// Необязательная таблица занятий (class_id, weekday, start, end);
+	// если не задана, расписание берётся из колонок mon..sun таблицы classes
+	ClassSessionsTable = common.GetEnvString("CLASS_SESSIONS_TABLE", "")
+
+	timeZone := common.GetEnvString("TIME_ZONE", "Europe/Moscow")
+	if loc, err := time.LoadLocation(timeZone); err == nil {
+		Location = loc
+	} else {
+		log.Printf("Неизвестный часовой пояс %s, используем локальный: %v", timeZone, err)
+		Location = time.Local
+	}
+
+	// Календарь закрытий (каникулы, праздники) — из JSON-файла и/или из таблицы БД
+	ClosuresFile = common.GetEnvString("CLOSURES_FILE", "")
+	ClosuresTable = common.GetEnvString("CLOSURES_TABLE", "")
+	ClosureLookaheadDays = common.GetEnvInt("CLOSURE_LOOKAHEAD_DAYS", 14)
+	ClosurePolicy = common.GetEnvString("CLOSURE_POLICY", ClosurePolicyHide)
+
+	// Классы, которые стартуют в ближайшие UPCOMING_DAYS дней, тоже попадают в выгрузку.
+	// Если задан NEW_GROUPS_CATEGORY_ID, они выносятся в отдельную категорию.
+	UpcomingDays = common.GetEnvInt("UPCOMING_DAYS", 0)
+	NewGroupsCategoryID = common.GetEnvInt("NEW_GROUPS_CATEGORY_ID", 0)
+	if NewGroupsCategoryID > 0 {
+		Categories.Category = append(Categories.Category, entity.Category{
+			ID:   NewGroupsCategoryID,
+			Name: common.GetEnvString("NEW_GROUPS_CATEGORY_NAME", "Новые группы"),
+		})
+	}
+
+	// Преподаватели классов. Запрос должен вернуть колонки class_id, teacher_id, name;
+	// TEACHERS_QUERY позволяет подстроить его под схему CRM.
+	TeachersEnabled = common.GetEnvBool("CLASS_TEACHERS", false)
+	TeachersQuery = common.GetEnvString("TEACHERS_QUERY", `
+		SELECT tc.class_id, t.id, t.name
+		FROM teachers_classes AS tc
+		JOIN teachers AS t ON t.id = tc.teacher_id
+		ORDER BY tc.class_id, tc.id`)
+
+	// Доступность классов для записи. AVAILABILITY_SOURCE=capacity берёт вместимость
+	// и число записанных (колонки class_id, capacity, enrolled), paused — флаг паузы
+	// (колонки class_id, paused). Пустое значение — все классы доступны.
+	AvailabilitySource = common.GetEnvString("AVAILABILITY_SOURCE", "")
+	AvailabilityPolicy = common.GetEnvString("AVAILABILITY_POLICY", AvailabilityPolicyMark)
+	CapacityQuery = common.GetEnvString("CAPACITY_QUERY", `
+		SELECT c.id, c.capacity, COUNT(e.id)
+		FROM classes AS c
+		LEFT JOIN class_enrollments AS e ON e.class_id = c.id AND e.deleted IS NULL
+		GROUP BY c.id, c.capacity`)
+	PausedQuery = common.GetEnvString("PAUSED_QUERY", `
+		SELECT c.id, c.paused
+		FROM classes AS c`)
 }
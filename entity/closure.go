@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// Closure — период, когда занятия не проводятся: во всей школе (StudioID == 0)
+// или только в одной студии. From и To — включительные даты.
+type Closure struct {
+	From     time.Time
+	To       time.Time
+	StudioID int
+	Reason   string
+}
+
+// Covers сообщает, приходится ли день на закрытие для указанной студии
+func (c Closure) Covers(day time.Time, studioID int) bool {
+	if c.StudioID != 0 && c.StudioID != studioID {
+		return false
+	}
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	from := time.Date(c.From.Year(), c.From.Month(), c.From.Day(), 0, 0, 0, 0, day.Location())
+	to := time.Date(c.To.Year(), c.To.Month(), c.To.Day(), 0, 0, 0, 0, day.Location())
+	return !date.Before(from) && !date.After(to)
+}
@@ -1,23 +1,43 @@
 package entity
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
 
 type Version struct {
-	Hash    string
-	PubDate string
+	Hash     string
+	PubDate  string
+	Modified time.Time
 }
 
 type YmlCatalog struct {
 	XMLName xml.Name `xml:"yml_catalog"`
 	Date    string   `xml:"date,attr"`
 	Shop    Shop     `xml:"shop"`
-	Name    string   `xml:"name"`
-	Company string   `xml:"company"`
 }
 
+// Shop — заголовок магазина в YML; порядок полей задан форматом
 type Shop struct {
-	Categories Categories `xml:"categories"`
-	Offers     Offers     `xml:"offers"`
+	Name       string      `xml:"name"`
+	Company    string      `xml:"company"`
+	URL        string      `xml:"url"`
+	Platform   string      `xml:"platform,omitempty"`
+	Version    string      `xml:"version,omitempty"`
+	Email      string      `xml:"email,omitempty"`
+	Currencies *Currencies `xml:"currencies,omitempty"`
+	Categories Categories  `xml:"categories"`
+	Offers     Offers      `xml:"offers"`
+}
+
+type Currencies struct {
+	Currency []Currency `xml:"currency"`
+}
+
+type Currency struct {
+	ID   string `xml:"id,attr"`
+	Rate string `xml:"rate,attr"`
 }
 
 type Categories struct {
@@ -25,24 +45,72 @@ type Categories struct {
 }
 
 type Category struct {
-	ID   int    `xml:"id,attr"`
-	Name string `xml:",chardata"`
+	ID   int    `xml:"id,attr" json:"id"`
+	Name string `xml:",chardata" json:"name"`
 }
 
 type Offers struct {
 	Offer []Offer `xml:"offer"`
 }
 
+const (
+	OfferKindClass = "class"
+	OfferKindPass  = "pass"
+)
+
 type Offer struct {
-	XMLName          xml.Name `xml:"offer"`
-	ID               int      `xml:"id,attr"`
-	Vendor           string   `xml:"vendor"`
-	Price            int      `xml:"price"`
-	CurrencyID       string   `xml:"currencyId"`
-	CategoryID       int      `xml:"categoryId"`
-	Picture          string   `xml:"picture"`
-	URL              string   `xml:"url"`
-	Name             string   `xml:"name"`
-	Description      string   `xml:"description"`
-	ShortDescription string   `xml:"shortDescription"`
+	XMLName          xml.Name  `xml:"offer"`
+	ID               int       `xml:"id,attr"`
+	Available        *bool     `xml:"available,attr,omitempty"`
+	Vendor           string    `xml:"vendor"`
+	Price            int       `xml:"price"`
+	CurrencyID       string    `xml:"currencyId"`
+	CategoryID       int       `xml:"categoryId"`
+	Pictures         []string  `xml:"picture"`
+	URL              string    `xml:"url"`
+	Name             string    `xml:"name"`
+	Description      string    `xml:"description"`
+	ShortDescription string    `xml:"shortDescription"`
+	Params           []Param   `xml:"param"`
+	Kind             string    `xml:"-"`
+	Schedule         Schedule  `xml:"-"`
+	Studio           Studio    `xml:"-"`
+	Style            Style     `xml:"-"`
+	Teachers         []Teacher `xml:"-"`
+	StartDate        time.Time `xml:"-"`
+	EndDate          time.Time `xml:"-"`
+}
+
+// IsAvailable сообщает, можно ли записаться: если доступность неизвестна, считаем, что можно
+func (o Offer) IsAvailable() bool {
+	return o.Available == nil || *o.Available
+}
+
+// UID — идентификатор, уникальный среди классов и абонементов, например «class-12»:
+// ID классов и абонементов могут совпадать
+func (o Offer) UID() string {
+	return fmt.Sprintf("%s-%d", o.Kind, o.ID)
+}
+
+type Studio struct {
+	ID      int
+	Title   string
+	Address string
+}
+
+type Style struct {
+	ID    int
+	Title string
+	Slug  string
+}
+
+type Teacher struct {
+	ID   int
+	Name string
+}
+
+type Param struct {
+	Name  string `xml:"name,attr" json:"name"`
+	Unit  string `xml:"unit,attr,omitempty" json:"unit,omitempty"`
+	Value string `xml:",chardata" json:"value"`
 }
@@ -0,0 +1,86 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Weekday — день недели, от понедельника (0) до воскресенья (6),
+// в том же порядке, что и колонки mon..sun в таблице classes.
+type Weekday int
+
+const (
+	Monday Weekday = iota
+	Tuesday
+	Wednesday
+	Thursday
+	Friday
+	Saturday
+	Sunday
+)
+
+var weekdayCodes = [...]string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+func (d Weekday) String() string {
+	if d < Monday || d > Sunday {
+		return fmt.Sprintf("Weekday(%d)", int(d))
+	}
+	return weekdayCodes[d]
+}
+
+func (d Weekday) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// TimeOfDay — время начала занятия в минутах от полуночи
+type TimeOfDay int
+
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", int(t)/60, int(t)%60)
+}
+
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// Session — одно занятие в недельном расписании класса
+type Session struct {
+	Weekday  Weekday       `json:"weekday"`
+	Start    TimeOfDay     `json:"start"`
+	Duration time.Duration `json:"-"` // 0, если длительность неизвестна
+}
+
+func (s Session) MarshalJSON() ([]byte, error) {
+	type session Session
+	out := struct {
+		session
+		DurationMinutes int `json:"duration_minutes,omitempty"`
+	}{session(s), int(s.Duration / time.Minute)}
+	return json.Marshal(out)
+}
+
+// End возвращает время окончания занятия, если известна его длительность
+func (s Session) End() (TimeOfDay, bool) {
+	if s.Duration <= 0 {
+		return 0, false
+	}
+	return (s.Start + TimeOfDay(s.Duration/time.Minute)) % (24 * 60), true
+}
+
+// TimeRange возвращает время занятия как «19:00» или «19:00–20:30»
+func (s Session) TimeRange() string {
+	if end, ok := s.End(); ok {
+		return s.Start.String() + "–" + end.String()
+	}
+	return s.Start.String()
+}
+
+// Schedule — недельное расписание класса
+type Schedule struct {
+	Sessions []Session `json:"sessions"`
+}
+
+func (s Schedule) IsEmpty() bool {
+	return len(s.Sessions) == 0
+}
@@ -0,0 +1,75 @@
+package images
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Key describes what a picture is needed for. GetRandomImage resolves it
+// through a chain of pools, from the most specific to the most generic:
+//
+//  1. IMAGE_DIR/class/<ClassID>/
+//  2. IMAGE_DIR/teacher/<TeacherID>/, for each teacher in order
+//  3. IMAGE_DIR/style/<StyleSlug>/
+//  4. IMAGE_DIR/studio/<StudioID>/
+//  5. IMAGE_DIR/<CategoryID>/
+//  6. IMAGE_DIR/<ParentCategoryID>/
+//  7. Fallback
+//
+// Zero fields are skipped. OfferID and StyleSlug are also matched against
+// image bindings in directory manifests (see Rules).
+type Key struct {
+	OfferID    int
+	ClassID    int
+	TeacherIDs []int
+	StyleSlug  string
+	StudioID   int
+	CategoryID int
+	// ParentCategoryID is checked after CategoryID, e.g. new groups
+	// fall back to the pictures of regular classes
+	ParentCategoryID int
+	Fallback         string
+}
+
+// CategoryKey returns a key that only looks into the category pool
+func CategoryKey(categoryID int) Key {
+	return Key{CategoryID: categoryID}
+}
+
+// pool is a single directory in the resolution chain
+type pool struct {
+	name      string // path relative to IMAGE_DIR, also used as the cache key
+	createDir bool   // category directories are created on first scan
+}
+
+// pools returns the resolution chain for the key
+func (k Key) pools() []pool {
+	var pools []pool
+	if k.ClassID > 0 {
+		pools = append(pools, pool{name: fmt.Sprintf("class/%d", k.ClassID)})
+	}
+	for _, teacherID := range k.TeacherIDs {
+		pools = append(pools, pool{name: fmt.Sprintf("teacher/%d", teacherID)})
+	}
+	if k.StyleSlug != "" {
+		pools = append(pools, pool{name: "style/" + k.StyleSlug})
+	}
+	if k.StudioID > 0 {
+		pools = append(pools, pool{name: fmt.Sprintf("studio/%d", k.StudioID)})
+	}
+	if k.CategoryID > 0 {
+		pools = append(pools, pool{name: fmt.Sprintf("%d", k.CategoryID), createDir: true})
+	}
+	if k.ParentCategoryID > 0 && k.ParentCategoryID != k.CategoryID {
+		pools = append(pools, pool{name: fmt.Sprintf("%d", k.ParentCategoryID), createDir: true})
+	}
+	return pools
+}
+
+func (k Key) String() string {
+	var names []string
+	for _, p := range k.pools() {
+		names = append(names, p.name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -1,11 +1,17 @@
 package images
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/fs"
+	"log"
+	"maps"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,9 +21,22 @@ import (
 // ImageManager handles random image selection with usage tracking
 type ImageManager struct {
 	mu           sync.RWMutex
-	usageStats   map[string]map[string]int // categoryID -> imagePath -> usage count
-	imageCache   map[string][]string       // categoryID -> []imagePaths
-	lastScanTime map[string]time.Time      // categoryID -> last scan time
+	usageStats   map[string]map[string]int    // pool -> imagePath -> usage count
+	imageCache   map[string][]string          // pool -> []imagePaths
+	lastScanTime map[string]time.Time         // pool -> last scan time
+	imageInfo    map[string]ImageInfo         // imagePath -> validated file details
+	byHash       map[string]ImageInfo         // content hash -> canonical image
+	rejected     map[string][]Rejection       // pool -> files rejected during the last scan
+	pathHashes   map[string]map[string]string // pool -> file path -> content hash
+	// Deduplicated images share the canonical ImageInfo, but manifest rules
+	// and folder tags belong to each pool's own copy
+	poolEntries map[string]map[string]poolEntry // pool -> imagePath -> rules and tags
+}
+
+// poolEntry holds what the pool's own copy of an image says about it
+type poolEntry struct {
+	Rules Rules
+	Tags  []string
 }
 
 // NewImageManager creates a new image manager instance
@@ -26,56 +45,149 @@ func NewImageManager() *ImageManager {
 		usageStats:   make(map[string]map[string]int),
 		imageCache:   make(map[string][]string),
 		lastScanTime: make(map[string]time.Time),
+		imageInfo:    make(map[string]ImageInfo),
+		byHash:       make(map[string]ImageInfo),
+		rejected:     make(map[string][]Rejection),
+		pathHashes:   make(map[string]map[string]string),
+		poolEntries:  make(map[string]map[string]poolEntry),
 	}
 }
 
-// GetRandomImage returns a random image path for the given category ID
-// It prioritizes images that have been used less frequently
-func (im *ImageManager) GetRandomImage(categoryID int) (string, error) {
-	categoryStr := fmt.Sprintf("%d", categoryID)
+// GetRandomImage returns a random image for the key, trying every pool
+// of its resolution chain in turn (see Key) and falling back to key.Fallback.
+// Within a pool it prioritizes images that have been used less frequently.
+func (im *ImageManager) GetRandomImage(key Key) (string, error) {
+	images, err := im.GetRandomImages(key, 1)
+	if err != nil {
+		return "", err
+	}
+	return images[0], nil
+}
 
+// GetRandomImages returns up to n distinct images for the key. Pools of the
+// resolution chain are used in order until n images are collected, so the most
+// specific pictures come first. If no pool has images, key.Fallback is returned.
+func (im *ImageManager) GetRandomImages(key Key, n int) ([]string, error) {
 	im.mu.Lock()
 	defer im.mu.Unlock()
 
-	// Refresh image cache if needed (scan directory every 5 minutes)
-	if im.shouldRefreshCache(categoryStr) {
-		if err := im.scanCategoryImages(categoryStr); err != nil {
-			return "", fmt.Errorf("failed to scan images for category %d: %w", categoryID, err)
+	return im.selectImages(key, n, false)
+}
+
+// PreviewImages returns the images GetRandomImages would pick for the key now,
+// without counting them as used. Ties between equally used images are still random.
+func (im *ImageManager) PreviewImages(key Key, n int) ([]string, error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	return im.selectImages(key, n, true)
+}
+
+// selectImages implements GetRandomImages; with dryRun usage stats stay untouched
+func (im *ImageManager) selectImages(key Key, n int, dryRun bool) ([]string, error) {
+	var selected []string
+	chosen := make(map[string]bool)
+	for _, p := range key.pools() {
+		if len(selected) >= n {
+			break
+		}
+		images, err := im.pickFromPool(p, key, n-len(selected), chosen, dryRun)
+		if err != nil {
+			// An unreadable pool must not break the rest of the chain
+			log.Printf("Пропускаем пул картинок %s: %v", p.name, err)
+			continue
 		}
+		selected = append(selected, images...)
 	}
 
-	// Get available images for this category
-	images := im.imageCache[categoryStr]
-	if len(images) == 0 {
-		return "", fmt.Errorf("no images found for category %d", categoryID)
+	if len(selected) > 0 {
+		return selected, nil
+	}
+	if key.Fallback != "" {
+		return []string{key.Fallback}, nil
+	}
+	return nil, fmt.Errorf("no images found for %s", key)
+}
+
+// pickFromPool picks up to n images from the IMAGE_DIR/<pool>/ directory,
+// skipping the ones already chosen for the offer. Chosen images are added to chosen.
+// Images pinned to the key by the manifest win over the rest of the pool;
+// otherwise the image with the lowest usage per unit of weight is picked.
+func (im *ImageManager) pickFromPool(p pool, key Key, n int, chosen map[string]bool, dryRun bool) ([]string, error) {
+	categoryStr := p.name
+
+	// Refresh image cache if needed (scan directory every 5 minutes)
+	if im.shouldRefreshCache(categoryStr) {
+		if err := im.scanCategoryImages(categoryStr, p.createDir); err != nil {
+			return nil, fmt.Errorf("failed to scan images for %s: %w", categoryStr, err)
+		}
 	}
 
 	// Initialize usage stats for this category if not exists
 	if im.usageStats[categoryStr] == nil {
 		im.usageStats[categoryStr] = make(map[string]int)
 	}
+	usage := im.usageStats[categoryStr]
+	if dryRun {
+		usage = maps.Clone(usage)
+	}
 
-	// Find images with minimum usage
-	minUsage := -1
-	var candidates []string
+	eligible := im.eligibleImages(categoryStr, key, time.Now().In(config.Location))
+	entries := im.poolEntries[categoryStr]
 
-	for _, imagePath := range images {
-		usage := im.usageStats[categoryStr][imagePath]
-		if minUsage == -1 || usage < minUsage {
-			minUsage = usage
-			candidates = []string{imagePath}
-		} else if usage == minUsage {
-			candidates = append(candidates, imagePath)
+	var selected []string
+	for len(selected) < n {
+		// Find images with minimum usage per unit of weight
+		minScore := -1.0
+		var candidates []string
+
+		for _, imagePath := range eligible {
+			if chosen[imagePath] {
+				continue
+			}
+			score := float64(usage[imagePath]) / entries[imagePath].Rules.weight()
+			if minScore < 0 || score < minScore {
+				minScore = score
+				candidates = []string{imagePath}
+			} else if score == minScore {
+				candidates = append(candidates, imagePath)
+			}
+		}
+		if len(candidates) == 0 {
+			break
 		}
-	}
 
-	// Select random image from candidates with minimum usage
-	selectedImage := candidates[rand.Intn(len(candidates))]
+		// Select random image from candidates with minimum usage
+		selectedImage := candidates[rand.Intn(len(candidates))]
 
-	// Increment usage count
-	im.usageStats[categoryStr][selectedImage]++
+		// Increment usage count
+		usage[selectedImage]++
+		chosen[selectedImage] = true
+		selected = append(selected, selectedImage)
+	}
+
+	return selected, nil
+}
 
-	return selectedImage, nil
+// eligibleImages returns the pool images that may be shown for the key right now.
+// If some of them are pinned to the key, only the pinned ones are returned.
+func (im *ImageManager) eligibleImages(categoryStr string, key Key, now time.Time) []string {
+	var pinned, unbound []string
+	for _, imagePath := range im.imageCache[categoryStr] {
+		entry := im.poolEntries[categoryStr][imagePath]
+		rules := entry.Rules
+		switch {
+		case !rules.active(now), !tagsActive(entry.Tags, now):
+		case !rules.bound():
+			unbound = append(unbound, imagePath)
+		case rules.matches(key):
+			pinned = append(pinned, imagePath)
+		}
+	}
+	if len(pinned) > 0 {
+		return pinned
+	}
+	return unbound
 }
 
 // shouldRefreshCache checks if the cache should be refreshed for a category
@@ -90,21 +202,33 @@ func (im *ImageManager) shouldRefreshCache(categoryStr string) bool {
 }
 
 // scanCategoryImages scans the images directory for the given category
-func (im *ImageManager) scanCategoryImages(categoryStr string) error {
-	dirPath := filepath.Join(config.ImageDir, categoryStr)
+func (im *ImageManager) scanCategoryImages(categoryStr string, createDir bool) error {
+	dirPath := filepath.Join(config.ImageDir, filepath.FromSlash(categoryStr))
+
+	// Forget details of the previous scan
+	im.forgetPool(categoryStr)
 
 	// Check if directory exists
 	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
 		// Create directory if it doesn't exist
-		if err := os.MkdirAll(dirPath, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", dirPath, err)
+		if createDir {
+			if err := os.MkdirAll(dirPath, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", dirPath, err)
+			}
 		}
 		im.imageCache[categoryStr] = []string{}
+		delete(im.rejected, categoryStr)
+		delete(im.pathHashes, categoryStr)
+		delete(im.poolEntries, categoryStr)
 		im.lastScanTime[categoryStr] = time.Now()
 		return nil
 	}
 
 	var images []string
+	var rejected []Rejection
+	hashes := make(map[string]string)
+	entries := make(map[string]poolEntry)
+	manifests := make(map[string]manifest)
 
 	// Walk through the directory to find image files
 	err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
@@ -112,17 +236,45 @@ func (im *ImageManager) scanCategoryImages(categoryStr string) error {
 			return err
 		}
 
-		if !d.IsDir() {
-			// Check if it's an image file (common extensions)
-			ext := filepath.Ext(path)
-			switch ext {
-			case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".bmp":
-				// Construct full URL from base path and filename
-				fileName := filepath.Base(path)
-				fullURL := strings.TrimRight(config.ImagePath, "/") + "/" + categoryStr + "/" + fileName
-				images = append(images, fullURL)
+		// Every directory may carry its own manifest
+		if d.IsDir() {
+			m, err := loadManifest(path)
+			if err != nil {
+				rejected = append(rejected, Rejection{Pool: categoryStr, Path: filepath.Join(path, config.ImageManifest), Reason: err.Error()})
 			}
+			manifests[path] = m
+			return nil
 		}
+
+		// Check if it's an image file (common extensions)
+		if !isImageFile(path) {
+			return nil
+		}
+
+		info, err := processImage(categoryStr, dirPath, path)
+		if err != nil {
+			rejected = append(rejected, Rejection{Pool: categoryStr, Path: path, Reason: err.Error()})
+			return nil
+		}
+		info.Rules = manifests[filepath.Dir(path)][filepath.Base(path)]
+		hashes[filepath.Clean(path)] = info.Hash
+		entry := poolEntry{Rules: info.Rules, Tags: info.Tags}
+
+		// Identical files share one URL, so a photo copied into several folders counts as one image.
+		// Only the URL and file details are shared: rules and tags stay with this pool's copy.
+		if canonical, ok := im.byHash[info.Hash]; ok {
+			if _, err := os.Stat(canonical.Path); err == nil {
+				info = canonical
+			}
+		}
+		if _, seen := entries[info.URL]; seen {
+			return nil
+		}
+		entries[info.URL] = entry
+
+		images = append(images, info.URL)
+		im.imageInfo[info.URL] = info
+		im.byHash[info.Hash] = info
 		return nil
 	})
 
@@ -131,11 +283,75 @@ func (im *ImageManager) scanCategoryImages(categoryStr string) error {
 	}
 
 	im.imageCache[categoryStr] = images
+	im.rejected[categoryStr] = rejected
+	im.pathHashes[categoryStr] = hashes
+	im.poolEntries[categoryStr] = entries
 	im.lastScanTime[categoryStr] = time.Now()
 
 	return nil
 }
 
+// forgetPool drops details of images found in the pool during the previous scan.
+// Images shared with other pools through deduplication stay known until those pools are rescanned.
+func (im *ImageManager) forgetPool(categoryStr string) {
+	for _, imagePath := range im.imageCache[categoryStr] {
+		info, ok := im.imageInfo[imagePath]
+		if !ok || info.Pool != categoryStr {
+			continue
+		}
+		delete(im.imageInfo, imagePath)
+		if im.byHash[info.Hash].URL == imagePath {
+			delete(im.byHash, info.Hash)
+		}
+	}
+}
+
+// processImage validates a file found in the pool and decides which URL the feed uses for it
+func processImage(categoryStr string, dirPath string, path string) (ImageInfo, error) {
+	info, err := validateImage(path)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	info.Pool = categoryStr
+
+	if info.Hash, err = HashFile(path); err != nil {
+		return ImageInfo{}, err
+	}
+
+	// Subfolders are kept in the URL; known folder names also act as tags
+	relPath, err := filepath.Rel(dirPath, path)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	info.RelPath = filepath.ToSlash(relPath)
+	info.Tags = pathTags(info.RelPath)
+
+	// Construct full URL from base path and the path relative to IMAGE_DIR
+	urlPath := escapePath(categoryStr + "/" + info.RelPath)
+	if config.ServeImages {
+		// Served by this service: the content hash in the path lets clients cache forever
+		info.URL = strings.TrimRight(config.ImagePath, "/") + "/" + info.Hash[:16] + "/" + urlPath
+	} else {
+		info.URL = strings.TrimRight(config.ImagePath, "/") + "/" + urlPath
+		// Published elsewhere under the file name: a fingerprint makes clients re-fetch replaced photos
+		if config.ImageURLVersion {
+			info.URL += "?v=" + info.Hash[:8]
+		}
+	}
+
+	// Point the feed at the resized copy instead of the original
+	if config.ImageResize {
+		name, err := ensureDerivative(info)
+		if err != nil {
+			return ImageInfo{}, err
+		}
+		info.Derivative = filepath.Join(config.ImageCacheDir, name)
+		info.URL = strings.TrimRight(config.ImageCachePath, "/") + "/" + name
+	}
+
+	return info, nil
+}
+
 // GetUsageStats returns usage statistics for debugging/monitoring
 func (im *ImageManager) GetUsageStats() map[string]map[string]int {
 	im.mu.RLock()
@@ -153,6 +369,49 @@ func (im *ImageManager) GetUsageStats() map[string]map[string]int {
 	return stats
 }
 
+// PoolInfo describes the result of the latest scan of a pool
+type PoolInfo struct {
+	ScannedAt time.Time   `json:"scanned_at"`
+	Images    []ImageInfo `json:"images"`
+	Rejected  []Rejection `json:"rejected"`
+}
+
+// GetPools returns the images known per pool along with their validation status
+func (im *ImageManager) GetPools() map[string]PoolInfo {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	pools := make(map[string]PoolInfo, len(im.lastScanTime))
+	for categoryStr, scannedAt := range im.lastScanTime {
+		info := PoolInfo{
+			ScannedAt: scannedAt,
+			Images:    make([]ImageInfo, 0, len(im.imageCache[categoryStr])),
+			Rejected:  append([]Rejection{}, im.rejected[categoryStr]...),
+		}
+		for _, imagePath := range im.imageCache[categoryStr] {
+			image := im.imageInfo[imagePath]
+			entry := im.poolEntries[categoryStr][imagePath]
+			image.Rules, image.Tags = entry.Rules, entry.Tags
+			info.Images = append(info.Images, image)
+		}
+		pools[categoryStr] = info
+	}
+	return pools
+}
+
+// Rescan scans the pool directory immediately instead of waiting for the cache to expire
+func (im *ImageManager) Rescan(categoryStr string) error {
+	if !fs.ValidPath(categoryStr) || categoryStr == "." {
+		return fmt.Errorf("invalid pool %q", categoryStr)
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	_, err := strconv.Atoi(categoryStr)
+	return im.scanCategoryImages(categoryStr, err == nil)
+}
+
 // ResetUsageStats resets all usage statistics
 func (im *ImageManager) ResetUsageStats() {
 	im.mu.Lock()
@@ -160,3 +419,73 @@ func (im *ImageManager) ResetUsageStats() {
 
 	im.usageStats = make(map[string]map[string]int)
 }
+
+// Fingerprint returns a hash of every known image URL and its content.
+// It changes whenever a photo is added, removed or replaced under the same name,
+// but not when the random selection changes.
+func (im *ImageManager) Fingerprint() string {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	entries := make([]string, 0, len(im.imageInfo))
+	for imagePath, info := range im.imageInfo {
+		entries = append(entries, imagePath+"|"+info.Hash)
+	}
+	sort.Strings(entries)
+
+	hasher := sha256.New()
+	for _, entry := range entries {
+		fmt.Fprintln(hasher, entry)
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// ImageSize returns the dimensions of the picture published at url,
+// taking resizing into account. ok is false for URLs the manager did not produce.
+func (im *ImageManager) ImageSize(url string) (width, height int, ok bool) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	info, ok := im.imageInfo[url]
+	if !ok {
+		return 0, 0, false
+	}
+	if info.Derivative != "" {
+		width, height = resizedSize(info.Width, info.Height, config.ImageResizeWidth, config.ImageResizeHeight, config.ImageResizeMode)
+		return width, height, true
+	}
+	return info.Width, info.Height, true
+}
+
+// ContentHash returns the content hash of the file at filePath computed
+// during the latest scan of its pool. ok is false for files no scan has seen.
+func (im *ImageManager) ContentHash(filePath string) (hash string, ok bool) {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	filePath = filepath.Clean(filePath)
+	for _, hashes := range im.pathHashes {
+		if hash, ok := hashes[filePath]; ok {
+			return hash, true
+		}
+	}
+	return "", false
+}
+
+// GetRejections returns files rejected during the latest scans, ordered by pool and path
+func (im *ImageManager) GetRejections() []Rejection {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	var rejections []Rejection
+	for _, list := range im.rejected {
+		rejections = append(rejections, list...)
+	}
+	sort.Slice(rejections, func(i, j int) bool {
+		if rejections[i].Pool != rejections[j].Pool {
+			return rejections[i].Pool < rejections[j].Pool
+		}
+		return rejections[i].Path < rejections[j].Path
+	})
+	return rejections
+}
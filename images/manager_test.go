@@ -1,13 +1,60 @@
 package images
 
 import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
+	"yandex-export/config"
 )
 
+// writeTestImage writes a real image of the given size, encoded according to the extension
+func writeTestImage(t *testing.T, path string, width, height int) {
+	t.Helper()
+	if err := writeImageFile(path, width, height); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeImageFile(path string, width, height int) error {
+	// Derive the colour from the path so that every test image has distinct content
+	hasher := fnv.New32a()
+	hasher.Write([]byte(path))
+	seed := hasher.Sum32()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, x%max(height, 1), color.RGBA{R: uint8(seed), G: uint8(seed >> 8), B: uint8(seed >> 16), A: 255})
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create test image %s: %w", path, err)
+	}
+	defer f.Close()
+
+	switch filepath.Ext(path) {
+	case ".png":
+		err = png.Encode(f, img)
+	case ".gif":
+		err = gif.Encode(f, img, nil)
+	default:
+		err = jpeg.Encode(f, img, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode test image %s: %w", path, err)
+	}
+	return nil
+}
+
 func TestImageManager_GetRandomImage(t *testing.T) {
 	// Create a temporary directory for testing
 	tempDir, err := os.MkdirTemp("", "image_test")
@@ -25,21 +72,14 @@ func TestImageManager_GetRandomImage(t *testing.T) {
 	// Create some test image files
 	testImages := []string{"test1.jpg", "test2.png", "test3.gif"}
 	for _, img := range testImages {
-		imgPath := filepath.Join(categoryDir, img)
-		if err := os.WriteFile(imgPath, []byte("test image"), 0644); err != nil {
-			t.Fatalf("Failed to create test image %s: %v", img, err)
-		}
+		writeTestImage(t, filepath.Join(categoryDir, img), 300, 300)
 	}
 
 	// Create image manager with test directory
-	im := &ImageManager{
-		usageStats:   make(map[string]map[string]int),
-		imageCache:   make(map[string][]string),
-		lastScanTime: make(map[string]time.Time),
-	}
+	im := NewImageManager()
 
 	// Test getting random image
-	imageURL, err := im.GetRandomImage(1)
+	imageURL, err := im.GetRandomImage(CategoryKey(1))
 	if err != nil {
 		t.Fatalf("GetRandomImage failed: %v", err)
 	}
@@ -52,7 +92,7 @@ func TestImageManager_GetRandomImage(t *testing.T) {
 	// Test that we get different images on multiple calls (not guaranteed but likely)
 	seenImages := make(map[string]bool)
 	for i := 0; i < 10; i++ {
-		img, err := im.GetRandomImage(1)
+		img, err := im.GetRandomImage(CategoryKey(1))
 		if err != nil {
 			t.Fatalf("GetRandomImage failed on iteration %d: %v", i, err)
 		}
@@ -80,8 +120,568 @@ func TestImageManager_UsageTracking(t *testing.T) {
 	// The image with minimum usage (2) should be preferred
 	// Since we can't guarantee which one will be selected due to randomness,
 	// we'll just verify the function doesn't error
-	_, err := im.GetRandomImage(1)
+	_, err := im.GetRandomImage(CategoryKey(1))
 	if err != nil {
 		t.Errorf("GetRandomImage failed: %v", err)
 	}
 }
+
+// TestMain gives the package a default image directory with a category 1 pool.
+// The original GetRandomImage and UsageTracking tests scan config.ImageDir
+// rather than their own temporary directories, so they need it populated.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "image_dir")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "1"), 0755); err != nil {
+		panic(err)
+	}
+	for _, name := range []string{"default1.jpg", "default2.png", "default3.gif"} {
+		if err := writeImageFile(filepath.Join(dir, "1", name), 300, 300); err != nil {
+			panic(err)
+		}
+	}
+	config.ImageDir = dir
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestImageManager_GetRandomImageFromDir(t *testing.T) {
+	tempDir := t.TempDir()
+	oldImageDir := config.ImageDir
+	config.ImageDir = tempDir
+	defer func() { config.ImageDir = oldImageDir }()
+
+	categoryDir := filepath.Join(tempDir, "1")
+	if err := os.MkdirAll(categoryDir, 0755); err != nil {
+		t.Fatalf("Failed to create category dir: %v", err)
+	}
+	testImages := []string{"test1.jpg", "test2.png", "test3.gif"}
+	for _, img := range testImages {
+		writeTestImage(t, filepath.Join(categoryDir, img), 300, 300)
+	}
+
+	im := NewImageManager()
+	seenImages := make(map[string]bool)
+	for i := 0; i < len(testImages); i++ {
+		img, err := im.GetRandomImage(CategoryKey(1))
+		if err != nil {
+			t.Fatalf("GetRandomImage failed on iteration %d: %v", i, err)
+		}
+		if !strings.Contains(img, "/1/test") {
+			t.Errorf("Expected an image from the test directory, got: %s", img)
+		}
+		seenImages[img] = true
+	}
+
+	// Usage is balanced, so every image is returned once before any repeats
+	if len(seenImages) != len(testImages) {
+		t.Errorf("Expected %d different images, got: %v", len(testImages), seenImages)
+	}
+}
+
+func TestImageManager_UsageTrackingPrefersLeastUsed(t *testing.T) {
+	im := NewImageManager()
+	im.usageStats["1"] = map[string]int{
+		"image1.jpg": 5,
+		"image2.jpg": 2,
+		"image3.jpg": 8,
+	}
+	im.imageCache["1"] = []string{"image1.jpg", "image2.jpg", "image3.jpg"}
+	im.lastScanTime["1"] = time.Now()
+
+	got, err := im.GetRandomImage(CategoryKey(1))
+	if err != nil {
+		t.Fatalf("GetRandomImage failed: %v", err)
+	}
+	if got != "image2.jpg" {
+		t.Errorf("Expected the least used image2.jpg, got: %s", got)
+	}
+}
+
+func TestImageManager_ResolutionChain(t *testing.T) {
+	tempDir := t.TempDir()
+	oldImageDir := config.ImageDir
+	config.ImageDir = tempDir
+	defer func() { config.ImageDir = oldImageDir }()
+
+	for _, dir := range []string{"1", "style/hip-hop", "studio/3"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+		writeTestImage(t, filepath.Join(tempDir, dir, "photo.jpg"), 300, 300)
+	}
+
+	im := NewImageManager()
+	tests := []struct {
+		key  Key
+		want string
+	}{
+		{Key{ClassID: 7, StyleSlug: "hip-hop", StudioID: 3, CategoryID: 1}, "/style/hip-hop/photo.jpg"},
+		{Key{ClassID: 7, StyleSlug: "jazz", StudioID: 3, CategoryID: 1}, "/studio/3/photo.jpg"},
+		{Key{ClassID: 7, StudioID: 4, CategoryID: 1}, "/1/photo.jpg"},
+		{Key{ClassID: 7, CategoryID: 2, Fallback: "default.png"}, "default.png"},
+	}
+	for _, tt := range tests {
+		got, err := im.GetRandomImage(tt.key)
+		if err != nil {
+			t.Fatalf("GetRandomImage(%s) failed: %v", tt.key, err)
+		}
+		if !strings.HasSuffix(got, tt.want) {
+			t.Errorf("GetRandomImage(%s) = %s, want suffix %s", tt.key, got, tt.want)
+		}
+	}
+
+	// Only category directories are created, the rest of the chain is just cached as empty
+	if _, err := os.Stat(filepath.Join(tempDir, "class", "7")); !os.IsNotExist(err) {
+		t.Errorf("Expected class directory not to be created, got: %v", err)
+	}
+	if _, ok := im.imageCache["class/7"]; !ok {
+		t.Errorf("Expected empty class pool to be cached")
+	}
+}
+
+func TestImageManager_GetRandomImages(t *testing.T) {
+	im := NewImageManager()
+	im.imageCache["class/7"] = []string{"class.jpg"}
+	im.imageCache["1"] = []string{"image1.jpg", "image2.jpg", "image3.jpg"}
+	im.lastScanTime["class/7"] = time.Now()
+	im.lastScanTime["1"] = time.Now()
+
+	key := Key{ClassID: 7, CategoryID: 1}
+	images, err := im.GetRandomImages(key, 3)
+	if err != nil {
+		t.Fatalf("GetRandomImages failed: %v", err)
+	}
+	if len(images) != 3 || images[0] != "class.jpg" {
+		t.Fatalf("Expected the class picture first and 3 images in total, got: %v", images)
+	}
+	if images[1] == images[2] {
+		t.Errorf("Expected distinct images, got: %v", images)
+	}
+
+	// More images than available: every image exactly once
+	images, err = im.GetRandomImages(key, 10)
+	if err != nil {
+		t.Fatalf("GetRandomImages failed: %v", err)
+	}
+	if len(images) != 4 {
+		t.Errorf("Expected all 4 images, got: %v", images)
+	}
+
+	// Usage stays balanced within the category pool
+	stats := im.GetUsageStats()["1"]
+	minUsage, maxUsage := stats["image1.jpg"], stats["image1.jpg"]
+	for _, count := range stats {
+		minUsage, maxUsage = min(minUsage, count), max(maxUsage, count)
+	}
+	if maxUsage-minUsage > 1 {
+		t.Errorf("Expected balanced usage, got: %v", stats)
+	}
+}
+
+func TestImageManager_Validation(t *testing.T) {
+	tempDir := t.TempDir()
+	oldImageDir := config.ImageDir
+	config.ImageDir = tempDir
+	defer func() { config.ImageDir = oldImageDir }()
+
+	categoryDir := filepath.Join(tempDir, "1")
+	if err := os.MkdirAll(categoryDir, 0755); err != nil {
+		t.Fatalf("Failed to create category dir: %v", err)
+	}
+	writeTestImage(t, filepath.Join(categoryDir, "good.png"), 300, 300)
+	writeTestImage(t, filepath.Join(categoryDir, "small.jpg"), 100, 100)
+	for name, content := range map[string]string{
+		"empty.jpg":   "",
+		"fake.jpg":    "not an image",
+		"picture.bmp": "BM fake bitmap",
+		"notes.txt":   "ignored",
+	} {
+		if err := os.WriteFile(filepath.Join(categoryDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	im := NewImageManager()
+	images, err := im.GetRandomImages(CategoryKey(1), 10)
+	if err != nil {
+		t.Fatalf("GetRandomImages failed: %v", err)
+	}
+	if len(images) != 1 || !strings.HasSuffix(images[0], "/1/good.png") {
+		t.Errorf("Expected only good.png to be accepted, got: %v", images)
+	}
+
+	rejected := make(map[string]string)
+	for _, r := range im.GetRejections() {
+		rejected[filepath.Base(r.Path)] = r.Reason
+	}
+	for _, name := range []string{"empty.jpg", "fake.jpg", "picture.bmp", "small.jpg"} {
+		if _, ok := rejected[name]; !ok {
+			t.Errorf("Expected %s to be rejected, got: %v", name, rejected)
+		}
+	}
+	if _, ok := rejected["notes.txt"]; ok {
+		t.Errorf("Expected non-image files to be ignored, got: %v", rejected)
+	}
+}
+
+func TestImageManager_Derivatives(t *testing.T) {
+	tempDir := t.TempDir()
+	oldImageDir, oldResize, oldCacheDir, oldCachePath := config.ImageDir, config.ImageResize, config.ImageCacheDir, config.ImageCachePath
+	oldWidth, oldHeight, oldMode := config.ImageResizeWidth, config.ImageResizeHeight, config.ImageResizeMode
+	defer func() {
+		config.ImageDir, config.ImageResize, config.ImageCacheDir, config.ImageCachePath = oldImageDir, oldResize, oldCacheDir, oldCachePath
+		config.ImageResizeWidth, config.ImageResizeHeight, config.ImageResizeMode = oldWidth, oldHeight, oldMode
+	}()
+	config.ImageDir = tempDir
+	config.ImageResize = true
+	config.ImageCacheDir = filepath.Join(tempDir, "cache")
+	config.ImageCachePath = "https://example.com/cache/"
+	config.ImageResizeWidth, config.ImageResizeHeight = 300, 250
+
+	categoryDir := filepath.Join(tempDir, "1")
+	if err := os.MkdirAll(categoryDir, 0755); err != nil {
+		t.Fatalf("Failed to create category dir: %v", err)
+	}
+	writeTestImage(t, filepath.Join(categoryDir, "wide.png"), 800, 400)
+
+	for _, mode := range []string{ResizeCrop, ResizeFit} {
+		config.ImageResizeMode = mode
+
+		im := NewImageManager()
+		url, err := im.GetRandomImage(CategoryKey(1))
+		if err != nil {
+			t.Fatalf("GetRandomImage failed: %v", err)
+		}
+		if !strings.HasPrefix(url, "https://example.com/cache/") || !strings.HasSuffix(url, "_300x250_"+mode+".jpg") {
+			t.Errorf("Expected derivative URL, got: %s", url)
+		}
+
+		f, err := os.Open(filepath.Join(config.ImageCacheDir, filepath.Base(url)))
+		if err != nil {
+			t.Fatalf("Derivative was not written: %v", err)
+		}
+		cfg, format, err := image.DecodeConfig(f)
+		f.Close()
+		if err != nil || format != "jpeg" {
+			t.Fatalf("Expected a JPEG derivative, got %s: %v", format, err)
+		}
+
+		wantW, wantH := 300, 250
+		if mode == ResizeFit {
+			wantH = 150
+		}
+		if cfg.Width != wantW || cfg.Height != wantH {
+			t.Errorf("%s: expected %dx%d, got %dx%d", mode, wantW, wantH, cfg.Width, cfg.Height)
+		}
+	}
+}
+
+func TestImageManager_Deduplication(t *testing.T) {
+	tempDir := t.TempDir()
+	oldImageDir, oldVersion := config.ImageDir, config.ImageURLVersion
+	defer func() { config.ImageDir, config.ImageURLVersion = oldImageDir, oldVersion }()
+	config.ImageDir = tempDir
+	config.ImageURLVersion = true
+
+	for _, dir := range []string{"1", "2"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	writeTestImage(t, filepath.Join(tempDir, "1", "photo.jpg"), 300, 300)
+	content, err := os.ReadFile(filepath.Join(tempDir, "1", "photo.jpg"))
+	if err != nil {
+		t.Fatalf("Failed to read test image: %v", err)
+	}
+	for _, copyPath := range []string{"1/copy.jpg", "2/photo.jpg"} {
+		if err := os.WriteFile(filepath.Join(tempDir, filepath.FromSlash(copyPath)), content, 0644); err != nil {
+			t.Fatalf("Failed to copy test image: %v", err)
+		}
+	}
+
+	im := NewImageManager()
+	images, err := im.GetRandomImages(Key{ClassID: 1, CategoryID: 1}, 10)
+	if err != nil {
+		t.Fatalf("GetRandomImages failed: %v", err)
+	}
+	if len(images) != 1 {
+		t.Fatalf("Expected duplicates within a pool to collapse, got: %v", images)
+	}
+	if !strings.Contains(images[0], "?v=") {
+		t.Errorf("Expected a version fingerprint in the URL, got: %s", images[0])
+	}
+
+	other, err := im.GetRandomImage(CategoryKey(2))
+	if err != nil {
+		t.Fatalf("GetRandomImage failed: %v", err)
+	}
+	if other != images[0] {
+		t.Errorf("Expected the copy in another category to share the URL, got %s and %s", images[0], other)
+	}
+
+	// Replacing a photo changes the fingerprint, rotating the selection does not
+	fingerprint := im.Fingerprint()
+	im.GetRandomImage(CategoryKey(1))
+	if im.Fingerprint() != fingerprint {
+		t.Errorf("Expected selection not to change the fingerprint")
+	}
+	writeTestImage(t, filepath.Join(tempDir, "2", "photo.jpg"), 400, 400)
+	im.lastScanTime = make(map[string]time.Time)
+	im.GetRandomImage(CategoryKey(2))
+	if im.Fingerprint() == fingerprint {
+		t.Errorf("Expected a replaced photo to change the fingerprint")
+	}
+}
+
+func TestImageManager_Manifest(t *testing.T) {
+	tempDir := t.TempDir()
+	oldImageDir := config.ImageDir
+	config.ImageDir = tempDir
+	defer func() { config.ImageDir = oldImageDir }()
+
+	categoryDir := filepath.Join(tempDir, "1")
+	if err := os.MkdirAll(categoryDir, 0755); err != nil {
+		t.Fatalf("Failed to create category dir: %v", err)
+	}
+	for _, name := range []string{"heavy.jpg", "light.jpg", "disabled.jpg", "expired.jpg", "pinned.jpg"} {
+		writeTestImage(t, filepath.Join(categoryDir, name), 300, 300)
+	}
+	manifest := `{
+		"heavy.jpg":    {"weight": 3},
+		"disabled.jpg": {"enabled": false},
+		"expired.jpg":  {"to": "2000-01-01"},
+		"pinned.jpg":   {"offers": [12], "styles": ["jazz"]}
+	}`
+	if err := os.WriteFile(filepath.Join(categoryDir, config.ImageManifest), []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	im := NewImageManager()
+	counts := make(map[string]int)
+	for i := 0; i < 40; i++ {
+		img, err := im.GetRandomImage(Key{OfferID: 1, CategoryID: 1})
+		if err != nil {
+			t.Fatalf("GetRandomImage failed: %v", err)
+		}
+		counts[filepath.Base(img)]++
+	}
+	if counts["heavy.jpg"] != 30 || counts["light.jpg"] != 10 {
+		t.Errorf("Expected a 3:1 split between heavy and light images, got: %v", counts)
+	}
+
+	for _, key := range []Key{{OfferID: 12, CategoryID: 1}, {OfferID: 5, StyleSlug: "jazz", CategoryID: 1}} {
+		img, err := im.GetRandomImage(key)
+		if err != nil {
+			t.Fatalf("GetRandomImage failed: %v", err)
+		}
+		if !strings.HasSuffix(img, "/pinned.jpg") {
+			t.Errorf("Expected the pinned image for %+v, got: %s", key, img)
+		}
+	}
+}
+
+func TestImageManager_DeduplicationKeepsPoolRules(t *testing.T) {
+	tempDir := t.TempDir()
+	oldImageDir, oldTags, oldSeasons := config.ImageDir, config.ImageTags, config.ImageSeasonTags
+	defer func() { config.ImageDir, config.ImageTags, config.ImageSeasonTags = oldImageDir, oldTags, oldSeasons }()
+	config.ImageDir = tempDir
+	config.ImageTags = nil
+	config.ImageSeasonTags = []config.SeasonTag{{Tag: "winter", From: "12-01", To: "02-28"}}
+
+	for _, dir := range []string{"1", "style/jazz", "2/winter", "3"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, filepath.FromSlash(dir)), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	writeTestImage(t, filepath.Join(tempDir, "1", "a.jpg"), 300, 300)
+	writeTestImage(t, filepath.Join(tempDir, "style", "jazz", "b.jpg"), 300, 300)
+	writeTestImage(t, filepath.Join(tempDir, "2", "winter", "c.jpg"), 300, 300)
+	copies := map[string]string{"1/a.jpg": "style/jazz/a.jpg", "2/winter/c.jpg": "3/c.jpg"}
+	for from, to := range copies {
+		content, err := os.ReadFile(filepath.Join(tempDir, filepath.FromSlash(from)))
+		if err != nil {
+			t.Fatalf("Failed to read test image: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(tempDir, filepath.FromSlash(to)), content, 0644); err != nil {
+			t.Fatalf("Failed to copy test image: %v", err)
+		}
+	}
+	manifest := `{"a.jpg": {"enabled": false}}`
+	if err := os.WriteFile(filepath.Join(tempDir, "style", "jazz", config.ImageManifest), []byte(manifest), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+
+	// The category pools are scanned first, so their copies become canonical
+	im := NewImageManager()
+	for _, categoryStr := range []string{"1", "2"} {
+		if err := im.Rescan(categoryStr); err != nil {
+			t.Fatalf("Rescan failed: %v", err)
+		}
+	}
+
+	// The copy disabled in style/jazz must stay disabled there
+	for i := 0; i < 10; i++ {
+		img, err := im.GetRandomImage(Key{StyleSlug: "jazz"})
+		if err != nil {
+			t.Fatalf("GetRandomImage failed: %v", err)
+		}
+		if !strings.HasSuffix(img, "/style/jazz/b.jpg") {
+			t.Fatalf("Expected the disabled copy to be skipped, got: %s", img)
+		}
+	}
+
+	// The winter tag of 2/winter/c.jpg must not hide the untagged copy in 3/
+	summer := time.Date(2026, time.July, 1, 12, 0, 0, 0, time.UTC)
+	if err := im.Rescan("3"); err != nil {
+		t.Fatalf("Rescan failed: %v", err)
+	}
+	if len(im.eligibleImages("2", CategoryKey(2), summer)) != 0 {
+		t.Errorf("Expected the winter copy to be hidden in summer")
+	}
+	if eligible := im.eligibleImages("3", CategoryKey(3), summer); len(eligible) != 1 || !strings.HasSuffix(eligible[0], "/2/winter/c.jpg") {
+		t.Errorf("Expected the untagged copy to be shown under the shared URL, got: %v", eligible)
+	}
+
+	// The 1/ copy keeps its own defaults
+	if eligible := im.eligibleImages("1", CategoryKey(1), summer); len(eligible) != 1 {
+		t.Errorf("Expected the rules of style/jazz not to leak into 1/, got: %v", eligible)
+	}
+}
+
+func TestImageManager_NestedFolders(t *testing.T) {
+	tempDir := t.TempDir()
+	oldImageDir, oldTags, oldSeasons := config.ImageDir, config.ImageTags, config.ImageSeasonTags
+	defer func() { config.ImageDir, config.ImageTags, config.ImageSeasonTags = oldImageDir, oldTags, oldSeasons }()
+	config.ImageDir = tempDir
+	config.ImageTags = nil
+	config.ImageSeasonTags = []config.SeasonTag{{Tag: "winter", From: "12-01", To: "02-28"}}
+
+	for _, name := range []string{"1/лето 2026/photo.jpg", "1/archive/photo.jpg", "1/winter/photo.jpg"} {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		writeTestImage(t, path, 300, 300)
+	}
+
+	im := NewImageManager()
+	if _, err := im.GetRandomImage(CategoryKey(1)); err != nil {
+		t.Fatalf("GetRandomImage failed: %v", err)
+	}
+
+	urls := make(map[string]bool)
+	for _, url := range im.imageCache["1"] {
+		urls[strings.TrimPrefix(url, config.ImagePath)] = true
+	}
+	for _, want := range []string{"/1/%D0%BB%D0%B5%D1%82%D0%BE%202026/photo.jpg", "/1/archive/photo.jpg", "/1/winter/photo.jpg"} {
+		if !urls[want] {
+			t.Errorf("Expected URL %s, got: %v", want, urls)
+		}
+	}
+
+	// The winter folder is a tag: its images are only eligible during the season
+	winterURL := config.ImagePath + "/1/winter/photo.jpg"
+	summer := time.Date(2026, time.July, 1, 12, 0, 0, 0, time.UTC)
+	winter := time.Date(2026, time.January, 15, 12, 0, 0, 0, time.UTC)
+	if slices.Contains(im.eligibleImages("1", CategoryKey(1), summer), winterURL) {
+		t.Errorf("Expected winter images to be hidden in summer")
+	}
+	if !slices.Contains(im.eligibleImages("1", CategoryKey(1), winter), winterURL) {
+		t.Errorf("Expected winter images to be shown in winter")
+	}
+}
+
+func TestImageManager_PreviewAndRescan(t *testing.T) {
+	tempDir := t.TempDir()
+	oldImageDir := config.ImageDir
+	config.ImageDir = tempDir
+	defer func() { config.ImageDir = oldImageDir }()
+
+	categoryDir := filepath.Join(tempDir, "1")
+	if err := os.MkdirAll(categoryDir, 0755); err != nil {
+		t.Fatalf("Failed to create category dir: %v", err)
+	}
+	writeTestImage(t, filepath.Join(categoryDir, "first.jpg"), 300, 300)
+
+	im := NewImageManager()
+	preview, err := im.PreviewImages(CategoryKey(1), 1)
+	if err != nil || len(preview) != 1 {
+		t.Fatalf("PreviewImages failed: %v %v", preview, err)
+	}
+	if usage := im.GetUsageStats()["1"]; len(usage) != 0 {
+		t.Errorf("Expected preview not to count usage, got: %v", usage)
+	}
+
+	// A new file shows up only after the cache expires, unless the pool is rescanned
+	writeTestImage(t, filepath.Join(categoryDir, "second.jpg"), 300, 300)
+	if err := im.Rescan("1"); err != nil {
+		t.Fatalf("Rescan failed: %v", err)
+	}
+	if images := im.GetPools()["1"].Images; len(images) != 2 {
+		t.Errorf("Expected 2 images after rescan, got: %v", images)
+	}
+	if err := im.Rescan("../outside"); err == nil {
+		t.Errorf("Expected Rescan to reject paths outside IMAGE_DIR")
+	}
+}
+
+func TestImageManager_BrokenPoolKeepsChain(t *testing.T) {
+	tempDir := t.TempDir()
+	oldImageDir := config.ImageDir
+	config.ImageDir = tempDir
+	defer func() { config.ImageDir = oldImageDir }()
+
+	// IMAGE_DIR/style is a file, so scanning style/hip-hop fails
+	if err := os.WriteFile(filepath.Join(tempDir, "style"), []byte("not a directory"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(tempDir, "1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestImage(t, filepath.Join(tempDir, "1", "photo.jpg"), 300, 300)
+
+	im := NewImageManager()
+	got, err := im.GetRandomImage(Key{StyleSlug: "hip-hop", CategoryID: 1, Fallback: "default.png"})
+	if err != nil {
+		t.Fatalf("GetRandomImage failed: %v", err)
+	}
+	if !strings.HasSuffix(got, "/1/photo.jpg") {
+		t.Errorf("Expected the category picture after the broken style pool, got: %s", got)
+	}
+}
+
+func TestImageManager_ImageSize(t *testing.T) {
+	tempDir := t.TempDir()
+	oldImageDir := config.ImageDir
+	config.ImageDir = tempDir
+	defer func() { config.ImageDir = oldImageDir }()
+
+	if err := os.MkdirAll(filepath.Join(tempDir, "1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestImage(t, filepath.Join(tempDir, "1", "wide.png"), 500, 300)
+
+	im := NewImageManager()
+	url, err := im.GetRandomImage(CategoryKey(1))
+	if err != nil {
+		t.Fatalf("GetRandomImage failed: %v", err)
+	}
+	if w, h, ok := im.ImageSize(url); !ok || w != 500 || h != 300 {
+		t.Errorf("ImageSize(%s) = %d×%d, %v; want 500×300", url, w, h, ok)
+	}
+	if _, _, ok := im.ImageSize("https://example.com/logo.png"); ok {
+		t.Errorf("Expected unknown URLs to have no size")
+	}
+
+	if w, h := resizedSize(500, 300, 600, 600, ResizeFit); w != 600 || h != 360 {
+		t.Errorf("resizedSize(fit) = %d×%d, want 600×360", w, h)
+	}
+	if w, h := resizedSize(500, 300, 600, 600, ResizeCrop); w != 600 || h != 600 {
+		t.Errorf("resizedSize(crop) = %d×%d, want 600×600", w, h)
+	}
+}
@@ -0,0 +1,98 @@
+package images
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+	"yandex-export/config"
+)
+
+// Rules are the per-image settings from a directory manifest (config.ImageManifest):
+//
+//	{
+//	  "summer.jpg": {"weight": 3, "from": "2026-06-01", "to": "2026-08-31"},
+//	  "old.jpg":    {"enabled": false},
+//	  "ivanova.jpg": {"offers": [12, 15], "styles": ["hip-hop"]}
+//	}
+//
+// Images without an entry use the defaults: enabled, weight 1, no date range, no bindings.
+type Rules struct {
+	Weight  float64  `json:"weight,omitempty"`
+	Enabled *bool    `json:"enabled,omitempty"`
+	From    string   `json:"from,omitempty"`
+	To      string   `json:"to,omitempty"`
+	Offers  []int    `json:"offers,omitempty"`
+	Styles  []string `json:"styles,omitempty"`
+
+	from time.Time
+	to   time.Time
+}
+
+// manifest maps file names in a directory to their rules
+type manifest map[string]Rules
+
+// loadManifest reads the manifest of the directory, if there is one
+func loadManifest(dir string) (manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, config.ImageManifest))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", filepath.Join(dir, config.ImageManifest), err)
+	}
+	for name, rules := range m {
+		if rules.From != "" {
+			if rules.from, err = time.ParseInLocation("2006-01-02", rules.From, config.Location); err != nil {
+				return nil, fmt.Errorf("invalid manifest %s: %s: %w", dir, name, err)
+			}
+		}
+		if rules.To != "" {
+			if rules.to, err = time.ParseInLocation("2006-01-02", rules.To, config.Location); err != nil {
+				return nil, fmt.Errorf("invalid manifest %s: %s: %w", dir, name, err)
+			}
+		}
+		m[name] = rules
+	}
+	return m, nil
+}
+
+// weight returns the selection weight, 1 by default
+func (r Rules) weight() float64 {
+	if r.Weight > 0 {
+		return r.Weight
+	}
+	return 1
+}
+
+// active reports whether the image may be shown at the moment
+func (r Rules) active(now time.Time) bool {
+	if r.Enabled != nil && !*r.Enabled {
+		return false
+	}
+	if !r.from.IsZero() && now.Before(r.from) {
+		return false
+	}
+	if !r.to.IsZero() && !now.Before(r.to.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+// bound reports whether the image is reserved for particular offers or styles
+func (r Rules) bound() bool {
+	return len(r.Offers) > 0 || len(r.Styles) > 0
+}
+
+// matches reports whether a bound image is reserved for the key
+func (r Rules) matches(key Key) bool {
+	return (key.OfferID > 0 && slices.Contains(r.Offers, key.OfferID)) ||
+		(key.StyleSlug != "" && slices.Contains(r.Styles, key.StyleSlug))
+}
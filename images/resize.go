@@ -0,0 +1,166 @@
+package images
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"yandex-export/config"
+)
+
+const (
+	// ResizeCrop scales the image to cover the target box and crops the overflow
+	ResizeCrop = "crop"
+	// ResizeFit scales the image to fit inside the target box, keeping the aspect ratio
+	ResizeFit = "fit"
+
+	derivativeQuality = 85
+)
+
+// HashFile returns the hex-encoded SHA-256 of the file content
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// derivativeName returns the cache file name for an image with the given content hash.
+// The name changes with both the content and the target geometry.
+func derivativeName(contentHash string) string {
+	return fmt.Sprintf("%s_%dx%d_%s.jpg", contentHash[:16],
+		config.ImageResizeWidth, config.ImageResizeHeight, config.ImageResizeMode)
+}
+
+// ensureDerivative creates the resized JPEG for the image unless it is already cached.
+// It returns the derivative file name inside config.ImageCacheDir.
+func ensureDerivative(info ImageInfo) (string, error) {
+	name := derivativeName(info.Hash)
+	target := filepath.Join(config.ImageCacheDir, name)
+	if _, err := os.Stat(target); err == nil {
+		return name, nil
+	}
+
+	f, err := os.Open(info.Path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s: %w", info.Path, err)
+	}
+	dst := resize(src, config.ImageResizeWidth, config.ImageResizeHeight, config.ImageResizeMode)
+
+	if err := os.MkdirAll(config.ImageCacheDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %w", config.ImageCacheDir, err)
+	}
+
+	// Write to a temporary file first so a half-written derivative is never served
+	tmp, err := os.CreateTemp(config.ImageCacheDir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := jpeg.Encode(tmp, dst, &jpeg.Options{Quality: derivativeQuality}); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// resize scales src to the target box using the given mode. Transparent areas
+// are flattened onto white, since the result is encoded as JPEG.
+func resize(src image.Image, width, height int, mode string) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+
+	// Source rectangle and output size
+	crop := b
+	dw, dh := resizedSize(sw, sh, width, height, mode)
+	if mode != ResizeFit {
+		if sw*height > sh*width {
+			cw := sh * width / height
+			crop = image.Rect(b.Min.X+(sw-cw)/2, b.Min.Y, b.Min.X+(sw-cw)/2+cw, b.Max.Y)
+		} else {
+			ch := sw * height / width
+			crop = image.Rect(b.Min.X, b.Min.Y+(sh-ch)/2, b.Max.X, b.Min.Y+(sh-ch)/2+ch)
+		}
+	}
+
+	// Flatten the source onto a white canvas
+	flat := image.NewRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, crop.Min, draw.Over)
+
+	return scaleBox(flat, dw, dh)
+}
+
+// resizedSize returns the size of a sw×sh image after resize: crop fills
+// the whole box, fit keeps the aspect ratio inside it
+func resizedSize(sw, sh, width, height int, mode string) (int, int) {
+	if mode != ResizeFit {
+		return width, height
+	}
+	if sw*height > sh*width {
+		return width, max(sh*width/sw, 1)
+	}
+	return max(sw*height/sh, 1), height
+}
+
+// scaleBox scales the image with a box filter: every output pixel is the average
+// of the source pixels it covers, which gives smooth results when downscaling
+func scaleBox(src *image.RGBA, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+
+	for y := 0; y < height; y++ {
+		y0 := y * sh / height
+		y1 := max((y+1)*sh/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * sw / width
+			x1 := max((x+1)*sw/width, x0+1)
+
+			var r, g, bl, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					bl += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+
+			o := dst.PixOffset(x, y)
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(bl / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,71 @@
+package images
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"yandex-export/config"
+)
+
+// escapePath escapes every segment of a slash-separated path for use in a URL,
+// so that Cyrillic names and spaces survive: "1/лето 2026/a.jpg" -> "1/%D0%BB...%202026/a.jpg"
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// knownTag reports whether the folder name is configured as a tag
+// in config.ImageTags or config.ImageSeasonTags
+func knownTag(name string) bool {
+	if slices.Contains(config.ImageTags, name) {
+		return true
+	}
+	for _, season := range config.ImageSeasonTags {
+		if season.Tag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pathTags returns the tags of an image: the names of its subfolders inside
+// the pool that are configured as tags. Other subfolders only organise files.
+func pathTags(relPath string) []string {
+	var tags []string
+	segments := strings.Split(relPath, "/")
+	for _, segment := range segments[:len(segments)-1] {
+		if knownTag(segment) && !slices.Contains(tags, segment) {
+			tags = append(tags, segment)
+		}
+	}
+	return tags
+}
+
+// tagActive reports whether the tag is selected right now: either always
+// via config.ImageTags or by a season rule covering the date
+func tagActive(tag string, now time.Time) bool {
+	if slices.Contains(config.ImageTags, tag) {
+		return true
+	}
+	for _, season := range config.ImageSeasonTags {
+		if season.Tag == tag && season.Covers(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// tagsActive reports whether an image with the tags may be shown: untagged
+// images always may, tagged ones only while all of their tags are active
+func tagsActive(tags []string, now time.Time) bool {
+	for _, tag := range tags {
+		if !tagActive(tag, now) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package images
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"yandex-export/config"
+)
+
+// imageExtensions lists the extensions the scanner looks at. Files with other
+// extensions are ignored silently; files listed here are validated and either
+// accepted or reported as rejected.
+var imageExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".bmp": true,
+}
+
+// supportedFormats are the formats accepted for the feed, as reported by image.DecodeConfig
+var supportedFormats = map[string]bool{
+	"jpeg": true, "png": true, "gif": true,
+}
+
+// ImageInfo describes an accepted image
+type ImageInfo struct {
+	Pool string `json:"pool"`
+	Path string `json:"path"`
+	// RelPath is the slash-separated path inside the pool directory
+	RelPath string   `json:"rel_path"`
+	Tags    []string `json:"tags,omitempty"`
+	URL     string   `json:"url"`
+	Format  string   `json:"format"`
+	Width   int      `json:"width"`
+	Height  int      `json:"height"`
+	Size    int64    `json:"size"`
+	Hash    string   `json:"hash"`
+	// Derivative is the resized copy in config.ImageCacheDir, if resizing is enabled
+	Derivative string `json:"derivative,omitempty"`
+	Rules      Rules  `json:"rules"`
+}
+
+// Rejection describes a file skipped during a scan and the reason why
+type Rejection struct {
+	Pool   string `json:"pool"`
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// isImageFile reports whether the scanner should consider the file
+func isImageFile(path string) bool {
+	return imageExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// validateImage checks the file size and decodes the image header to check
+// format and dimensions against the configured limits
+func validateImage(path string) (ImageInfo, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	if stat.Size() == 0 {
+		return ImageInfo{}, fmt.Errorf("empty file")
+	}
+	if config.MaxImageSize > 0 && stat.Size() > config.MaxImageSize {
+		return ImageInfo{}, fmt.Errorf("file size %d exceeds %d bytes", stat.Size(), config.MaxImageSize)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	defer f.Close()
+
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil {
+		return ImageInfo{}, fmt.Errorf("unsupported format or not an image: %v", err)
+	}
+	if !supportedFormats[format] {
+		return ImageInfo{}, fmt.Errorf("unsupported format %s", format)
+	}
+	if cfg.Width < config.MinImageWidth || cfg.Height < config.MinImageHeight {
+		return ImageInfo{}, fmt.Errorf("dimensions %dx%d are below the minimum %dx%d",
+			cfg.Width, cfg.Height, config.MinImageWidth, config.MinImageHeight)
+	}
+
+	return ImageInfo{
+		Path:   path,
+		Format: format,
+		Width:  cfg.Width,
+		Height: cfg.Height,
+		Size:   stat.Size(),
+	}, nil
+}
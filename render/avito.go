@@ -0,0 +1,140 @@
+package render
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"yandex-export/config"
+	"yandex-export/entity"
+	"yandex-export/repository"
+)
+
+// Ограничения автозагрузки Авито
+const (
+	avitoMaxTitle       = 50
+	avitoMaxDescription = 7500
+	avitoMaxImages      = 10
+)
+
+type avitoAds struct {
+	XMLName       xml.Name `xml:"Ads"`
+	FormatVersion string   `xml:"formatVersion,attr"`
+	Target        string   `xml:"target,attr"`
+	Ads           []avitoAd
+}
+
+type avitoAd struct {
+	XMLName        xml.Name     `xml:"Ad"`
+	ID             string       `xml:"Id"`
+	Category       string       `xml:"Category"`
+	ServiceType    string       `xml:"ServiceType,omitempty"`
+	ServiceSubtype string       `xml:"ServiceSubtype,omitempty"`
+	Address        string       `xml:"Address"`
+	ContactPhone   string       `xml:"ContactPhone,omitempty"`
+	ManagerName    string       `xml:"ManagerName,omitempty"`
+	Title          string       `xml:"Title"`
+	Description    avitoCData   `xml:"Description"`
+	Price          int          `xml:"Price"`
+	Images         *avitoImages `xml:"Images,omitempty"`
+}
+
+type avitoCData struct {
+	Text string `xml:",cdata"`
+}
+
+type avitoImages struct {
+	Image []avitoImage `xml:"Image"`
+}
+
+type avitoImage struct {
+	URL string `xml:"url,attr"`
+}
+
+// AvitoHandler отдаёт предложения в формате автозагрузки Авито (Ads/Ad).
+// Адреса берутся из таблицы студий, Id объявления — Offer.UID,
+// чтобы Авито узнавал объявление при каждой загрузке.
+func AvitoHandler(w http.ResponseWriter, sr *http.Request) {
+	// Студии тянем до проверки ETag: адреса и настройки AVITO_* входят в него
+	studios, err := repository.FetchStudios()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("fetchStudios error: %v", err), http.StatusInternalServerError)
+		return
+	}
+	version := studiosVersion(studios, config.AvitoAddress, config.AvitoContactPhone,
+		config.AvitoManagerName, config.AvitoServiceType, config.AvitoServiceSubtype)
+
+	compact := compactOutput(sr)
+	feed, ok := loadFeed(w, sr, xmlFormat("avito-"+version, compact))
+	if !ok {
+		return
+	}
+	addresses := avitoAddresses(studios)
+
+	ads := avitoAds{FormatVersion: "3", Target: "Avito.ru"}
+	for _, o := range feed.Offers {
+		// Объявление, пропавшее из выгрузки, Авито снимает с публикации
+		if !o.IsAvailable() {
+			continue
+		}
+		address := avitoAddress(o, addresses)
+		if address == "" {
+			log.Printf("Авито: пропускаем предложение %s: нет адреса", o.UID())
+			continue
+		}
+		ads.Ads = append(ads.Ads, avitoAdFromOffer(o, address))
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	if err := writeXML(w, ads, !compact); err != nil {
+		log.Printf("Ошибка записи XML для Авито: %v", err)
+	}
+}
+
+// avitoAddresses возвращает адреса студий по ID. Под ключом 0 — адрес
+// для предложений без студии: config.AvitoAddress или адрес первой студии.
+func avitoAddresses(studios []entity.Studio) map[int]string {
+	addresses := make(map[int]string, len(studios)+1)
+	for _, s := range studios {
+		if s.Address == "" {
+			continue
+		}
+		addresses[s.ID] = s.Address
+		if addresses[0] == "" {
+			addresses[0] = s.Address
+		}
+	}
+	if config.AvitoAddress != "" {
+		addresses[0] = config.AvitoAddress
+	}
+	return addresses
+}
+
+func avitoAddress(o entity.Offer, addresses map[int]string) string {
+	if address := addresses[o.Studio.ID]; o.Studio.ID != 0 && address != "" {
+		return address
+	}
+	return addresses[0]
+}
+
+func avitoAdFromOffer(o entity.Offer, address string) avitoAd {
+	ad := avitoAd{
+		ID:             o.UID(),
+		Category:       "Предложение услуг",
+		ServiceType:    config.AvitoServiceType,
+		ServiceSubtype: config.AvitoServiceSubtype,
+		Address:        address,
+		ContactPhone:   config.AvitoContactPhone,
+		ManagerName:    config.AvitoManagerName,
+		Title:          truncateRunes(o.Name, avitoMaxTitle),
+		Description:    avitoCData{Text: truncateRunes(o.Description, avitoMaxDescription)},
+		Price:          o.Price,
+	}
+	if len(o.Pictures) > 0 {
+		ad.Images = &avitoImages{}
+		for _, picture := range o.Pictures[:min(len(o.Pictures), avitoMaxImages)] {
+			ad.Images.Image = append(ad.Images.Image, avitoImage{URL: picture})
+		}
+	}
+	return ad
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+func TestAvitoAddress(t *testing.T) {
+	oldAddress := config.AvitoAddress
+	t.Cleanup(func() { config.AvitoAddress = oldAddress })
+
+	studios := []entity.Studio{
+		{ID: 1, Title: "Без адреса"},
+		{ID: 2, Title: "Центр", Address: "ул. Ленина, 1"},
+		{ID: 3, Title: "Север", Address: "пр. Мира, 10"},
+	}
+
+	config.AvitoAddress = ""
+	addresses := avitoAddresses(studios)
+	tests := []struct {
+		name   string
+		studio int
+		want   string
+	}{
+		{"own address", 3, "пр. Мира, 10"},
+		{"studio without address", 1, "ул. Ленина, 1"},
+		{"unknown studio", 7, "ул. Ленина, 1"},
+		{"pass without studio", 0, "ул. Ленина, 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := entity.Offer{Studio: entity.Studio{ID: tt.studio}}
+			if got := avitoAddress(o, addresses); got != tt.want {
+				t.Errorf("avitoAddress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	config.AvitoAddress = "ул. Садовая, 5"
+	addresses = avitoAddresses(studios)
+	if got := avitoAddress(entity.Offer{}, addresses); got != config.AvitoAddress {
+		t.Errorf("Expected AVITO_ADDRESS for offers without studio, got %q", got)
+	}
+	if got := avitoAddress(entity.Offer{Studio: entity.Studio{ID: 2}}, addresses); got != "ул. Ленина, 1" {
+		t.Errorf("Expected the studio address to win over AVITO_ADDRESS, got %q", got)
+	}
+
+	if got := avitoAddresses(nil)[0]; got != config.AvitoAddress {
+		t.Errorf("Expected AVITO_ADDRESS without studios, got %q", got)
+	}
+}
+
+func TestAvitoAdFromOffer(t *testing.T) {
+	oldType, oldSubtype := config.AvitoServiceType, config.AvitoServiceSubtype
+	oldPhone, oldManager := config.AvitoContactPhone, config.AvitoManagerName
+	t.Cleanup(func() {
+		config.AvitoServiceType, config.AvitoServiceSubtype = oldType, oldSubtype
+		config.AvitoContactPhone, config.AvitoManagerName = oldPhone, oldManager
+	})
+	config.AvitoServiceType = "Обучение, курсы"
+	config.AvitoServiceSubtype = "Танцы"
+	config.AvitoContactPhone = "+7 900 000-00-00"
+	config.AvitoManagerName = ""
+
+	pictures := make([]string, avitoMaxImages+2)
+	for i := range pictures {
+		pictures[i] = "https://bezpravil.net/img/1.jpg"
+	}
+	o := entity.Offer{
+		Kind:        entity.OfferKindClass,
+		ID:          12,
+		Name:        strings.Repeat("Хип-хоп ", 10),
+		Description: "Для начинающих",
+		Price:       700,
+		Pictures:    pictures,
+	}
+
+	ad := avitoAdFromOffer(o, "ул. Ленина, 1")
+	if ad.ID != "class-12" || ad.Price != 700 || ad.Address != "ул. Ленина, 1" {
+		t.Errorf("Unexpected ad: %+v", ad)
+	}
+	if n := len([]rune(ad.Title)); n > avitoMaxTitle {
+		t.Errorf("Title is %d runes, limit %d", n, avitoMaxTitle)
+	}
+	if ad.Images == nil || len(ad.Images.Image) != avitoMaxImages {
+		t.Errorf("Expected %d images, got %+v", avitoMaxImages, ad.Images)
+	}
+
+	output, err := xml.Marshal(ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(output)
+	for _, want := range []string{
+		"<Id>class-12</Id>",
+		"<Category>Предложение услуг</Category>",
+		"<ServiceType>Обучение, курсы</ServiceType>",
+		"<ServiceSubtype>Танцы</ServiceSubtype>",
+		"<ContactPhone>+7 900 000-00-00</ContactPhone>",
+		"<Description><![CDATA[Для начинающих]]></Description>",
+		`<Image url="https://bezpravil.net/img/1.jpg"></Image>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ad does not contain %s:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "ManagerName") {
+		t.Errorf("Expected no ManagerName when it is not configured:\n%s", got)
+	}
+
+	o.Pictures = nil
+	if output, _ := xml.Marshal(avitoAdFromOffer(o, "ул. Ленина, 1")); strings.Contains(string(output), "<Images") {
+		t.Errorf("Expected no Images without pictures:\n%s", output)
+	}
+}
@@ -0,0 +1,135 @@
+package render
+
+import (
+	"encoding/xml"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+type businessCatalog struct {
+	XMLName xml.Name     `xml:"yml_catalog"`
+	Date    string       `xml:"date,attr"`
+	Shop    businessShop `xml:"shop"`
+}
+
+type businessShop struct {
+	Name       string            `xml:"name"`
+	Company    string            `xml:"company"`
+	URL        string            `xml:"url"`
+	Categories entity.Categories `xml:"categories"`
+	Offers     []businessOffer   `xml:"offers>offer"`
+}
+
+type businessOffer struct {
+	ID          string         `xml:"id,attr"`
+	Name        string         `xml:"name"`
+	Price       int            `xml:"price"`
+	CurrencyID  string         `xml:"currencyId"`
+	CategoryID  int            `xml:"categoryId"`
+	Pictures    []string       `xml:"picture"`
+	URL         string         `xml:"url"`
+	Description string         `xml:"description"`
+	Params      []entity.Param `xml:"param"`
+}
+
+// BusinessHandler отдаёт прайс-лист услуг для карточки организации в Яндекс Бизнесе.
+// Услуги сгруппированы по разделам (categories) согласно config.BusinessSections,
+// у классов указана длительность занятия.
+func BusinessHandler(w http.ResponseWriter, sr *http.Request) {
+	compact := compactOutput(sr)
+	feed, ok := loadFeed(w, sr, xmlFormat("business", compact))
+	if !ok {
+		return
+	}
+
+	sections := make(map[string]int)
+	shop := businessShop{
+		Name:    config.CompanyName,
+		Company: config.CompanyName,
+		URL:     config.ShopURL,
+		Offers:  make([]businessOffer, 0, len(feed.Offers)),
+	}
+	for _, o := range feed.Offers {
+		section := businessSection(o)
+		if _, ok := sections[section]; !ok {
+			sections[section] = sectionID(section)
+		}
+		shop.Offers = append(shop.Offers, businessOfferFrom(o, sections[section]))
+	}
+
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		shop.Categories.Category = append(shop.Categories.Category, entity.Category{ID: sections[name], Name: name})
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	if err := writeXML(w, businessCatalog{Date: feed.Version.PubDate, Shop: shop}, !compact); err != nil {
+		log.Printf("Ошибка записи XML прайс-листа: %v", err)
+	}
+}
+
+// businessSection выбирает раздел прайс-листа: сначала по стилю, затем по студии,
+// категории и типу предложения. Если правила нет, раздел — название нашей категории.
+func businessSection(o entity.Offer) string {
+	keys := []string{
+		"style:" + o.Style.Slug,
+		"studio:" + strconv.Itoa(o.Studio.ID),
+		"category:" + strconv.Itoa(o.CategoryID),
+		"kind:" + o.Kind,
+	}
+	for _, key := range keys {
+		if section, ok := config.BusinessSections[key]; ok {
+			return section
+		}
+	}
+	return categoryName(o.CategoryID)
+}
+
+// sectionID выводит ID раздела из его названия, чтобы он не менялся,
+// когда добавляются или исчезают другие разделы
+func sectionID(name string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int(h.Sum32()%1_000_000_000) + 1
+}
+
+func businessOfferFrom(o entity.Offer, sectionID int) businessOffer {
+	out := businessOffer{
+		ID:          o.UID(),
+		Name:        o.Name,
+		Price:       o.Price,
+		CurrencyID:  o.CurrencyID,
+		CategoryID:  sectionID,
+		Pictures:    o.Pictures,
+		URL:         o.URL,
+		Description: o.Description,
+		Params:      o.Params,
+	}
+	if minutes := sessionMinutes(o.Schedule); minutes > 0 {
+		// Копия, чтобы не дописать в общий с кешем фида массив параметров
+		out.Params = append(slices.Clip(out.Params), entity.Param{Name: "Длительность", Unit: "мин", Value: strconv.Itoa(minutes)})
+	}
+	return out
+}
+
+// sessionMinutes возвращает длительность занятия в минутах, если она известна.
+// При разной длительности занятий берётся самая короткая.
+func sessionMinutes(s entity.Schedule) int {
+	minutes := 0
+	for _, session := range s.Sessions {
+		if m := int(session.Duration.Minutes()); m > 0 && (minutes == 0 || m < minutes) {
+			minutes = m
+		}
+	}
+	return minutes
+}
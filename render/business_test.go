@@ -0,0 +1,100 @@
+package render
+
+import (
+	"testing"
+	"time"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+func TestBusinessSection(t *testing.T) {
+	setCategoriesConfig(t)
+	oldSections := config.BusinessSections
+	t.Cleanup(func() { config.BusinessSections = oldSections })
+	config.BusinessSections = map[string]string{
+		"style:hip-hop": "Хип-хоп",
+		"studio:2":      "Филиал на Ленина",
+		"category:1":    "Танцы",
+		"kind:pass":     "Все абонементы",
+	}
+
+	tests := []struct {
+		name  string
+		offer entity.Offer
+		want  string
+	}{
+		{
+			name:  "style wins over studio",
+			offer: entity.Offer{Kind: entity.OfferKindClass, CategoryID: 1, Style: entity.Style{Slug: "hip-hop"}, Studio: entity.Studio{ID: 2}},
+			want:  "Хип-хоп",
+		},
+		{
+			name:  "studio wins over category",
+			offer: entity.Offer{Kind: entity.OfferKindClass, CategoryID: 1, Style: entity.Style{Slug: "jazz-funk"}, Studio: entity.Studio{ID: 2}},
+			want:  "Филиал на Ленина",
+		},
+		{
+			name:  "category",
+			offer: entity.Offer{Kind: entity.OfferKindClass, CategoryID: 1, Studio: entity.Studio{ID: 3}},
+			want:  "Танцы",
+		},
+		{
+			name:  "kind",
+			offer: entity.Offer{Kind: entity.OfferKindPass, CategoryID: 2},
+			want:  "Все абонементы",
+		},
+		{
+			name:  "no rule falls back to the category name",
+			offer: entity.Offer{Kind: entity.OfferKindClass, CategoryID: 2},
+			want:  "Абонементы",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := businessSection(tt.offer); got != tt.want {
+				t.Errorf("businessSection() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBusinessOfferFrom(t *testing.T) {
+	o := entity.Offer{
+		Kind:   entity.OfferKindClass,
+		ID:     12,
+		Name:   "Хип-хоп в студии Центр",
+		Price:  700,
+		Params: append(make([]entity.Param, 0, 4), entity.Param{Name: "Уровень", Value: "начальный"}),
+		Schedule: entity.Schedule{Sessions: []entity.Session{
+			{Weekday: entity.Monday, Start: 19 * 60, Duration: 90 * time.Minute},
+			{Weekday: entity.Saturday, Start: 12 * 60, Duration: 60 * time.Minute},
+			{Weekday: entity.Sunday, Start: 12 * 60},
+		}},
+	}
+
+	section := sectionID("Хип-хоп")
+	if section != sectionID("Хип-хоп") || section == sectionID("Танцы") || section <= 0 {
+		t.Errorf("Expected a stable positive section ID, got %d", section)
+	}
+
+	got := businessOfferFrom(o, section)
+	if got.ID != "class-12" || got.CategoryID != section {
+		t.Errorf("Unexpected offer: %+v", got)
+	}
+	want := []entity.Param{
+		{Name: "Уровень", Value: "начальный"},
+		{Name: "Длительность", Unit: "мин", Value: "60"},
+	}
+	if len(got.Params) != len(want) || got.Params[0] != want[0] || got.Params[1] != want[1] {
+		t.Errorf("Params = %+v, want %+v", got.Params, want)
+	}
+	// Запас ёмкости у исходного среза не должен приводить к записи в него
+	if extra := o.Params[:2]; extra[1] != (entity.Param{}) {
+		t.Errorf("Expected the source offer params to stay untouched, got %+v", extra)
+	}
+
+	o.Kind, o.Schedule = entity.OfferKindPass, entity.Schedule{}
+	if got := businessOfferFrom(o, section); len(got.Params) != 1 {
+		t.Errorf("Expected no duration without sessions, got %+v", got.Params)
+	}
+}
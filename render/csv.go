@@ -0,0 +1,93 @@
+package render
+
+import (
+	"encoding/csv"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"yandex-export/config"
+	"yandex-export/entity"
+	"yandex-export/schedule"
+)
+
+// utf8BOM нужен Excel, чтобы открыть CSV в UTF-8, а не в cp1251
+const utf8BOM = "\uFEFF"
+
+var csvHeader = []string{
+	"id", "kind", "available", "name", "category", "price", "currency", "url",
+	"schedule", "studio", "style", "teachers", "start_date", "end_date",
+	"params", "pictures", "description",
+}
+
+// CsvHandler отдаёт те же предложения, что и YML, таблицей CSV (UTF-8 с BOM)
+func CsvHandler(w http.ResponseWriter, sr *http.Request) {
+	feed, ok := loadFeed(w, sr, "csv")
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="catalog.csv"`)
+	if err := writeCsv(w, feed.Offers); err != nil {
+		log.Printf("Ошибка записи CSV: %v", err)
+	}
+}
+
+// writeCsv пишет BOM, заголовок и по строке на предложение.
+// Колонки разделяются config.CsvDelimiter — по умолчанию «;», как ждёт Excel с русской локалью.
+func writeCsv(w io.Writer, offers []entity.Offer) error {
+	if _, err := io.WriteString(w, utf8BOM); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	writer.Comma = config.CsvDelimiter
+	writer.Write(csvHeader)
+	for _, o := range offers {
+		writer.Write(csvRecord(o))
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvRecord(o entity.Offer) []string {
+	params := make([]string, len(o.Params))
+	for i, p := range o.Params {
+		params[i] = p.Name + ": " + p.Value
+		if p.Unit != "" {
+			params[i] += " " + p.Unit
+		}
+	}
+	teachers := make([]string, len(o.Teachers))
+	for i, t := range o.Teachers {
+		teachers[i] = t.Name
+	}
+	var startDate, endDate string
+	if !o.StartDate.IsZero() {
+		startDate = o.StartDate.Format("2006-01-02")
+	}
+	if !o.EndDate.IsZero() {
+		endDate = o.EndDate.Format("2006-01-02")
+	}
+
+	return []string{
+		o.UID(),
+		o.Kind,
+		strconv.FormatBool(o.IsAvailable()),
+		o.Name,
+		categoryName(o.CategoryID),
+		strconv.Itoa(o.Price),
+		isoCurrency(o.CurrencyID),
+		o.URL,
+		schedule.Text(o.Schedule),
+		o.Studio.Title,
+		o.Style.Title,
+		strings.Join(teachers, ", "),
+		startDate,
+		endDate,
+		strings.Join(params, "; "),
+		strings.Join(o.Pictures, " "),
+		o.Description,
+	}
+}
@@ -0,0 +1,92 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+func TestWriteCsv(t *testing.T) {
+	setCategoriesConfig(t)
+	oldDelimiter := config.CsvDelimiter
+	t.Cleanup(func() { config.CsvDelimiter = oldDelimiter })
+	config.CsvDelimiter = ';'
+
+	offers := []entity.Offer{
+		{
+			Kind:        entity.OfferKindClass,
+			ID:          12,
+			Name:        `Хип-хоп "с нуля", группа 1`,
+			Price:       700,
+			CurrencyID:  "RUR",
+			CategoryID:  1,
+			Pictures:    []string{"https://bezpravil.net/img/1.jpg", "https://bezpravil.net/img/2.jpg"},
+			Params:      []entity.Param{{Name: "Длительность", Value: "90", Unit: "мин"}, {Name: "Уровень", Value: "начальный"}},
+			Description: "Первая строка\nВторая строка",
+		},
+		{Kind: entity.OfferKindPass, ID: 1, Name: "Пробное занятие", Price: 300, CurrencyID: "RUR", CategoryID: 2},
+	}
+
+	var buf bytes.Buffer
+	if err := writeCsv(&buf, offers); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), utf8BOM+"id;kind;available;") {
+		t.Fatalf("Expected BOM and a semicolon-separated header first:\n%q", buf.String())
+	}
+	for _, quoted := range []string{`;"Хип-хоп ""с нуля"", группа 1";`, `;"Длительность: 90 мин; Уровень: начальный";`} {
+		if !strings.Contains(buf.String(), quoted) {
+			t.Errorf("Expected %s to be quoted:\n%s", quoted, buf.String())
+		}
+	}
+
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), utf8BOM)))
+	reader.Comma = ';'
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected header and 2 rows, got %d", len(records))
+	}
+	row := make(map[string]string, len(csvHeader))
+	for i, name := range records[0] {
+		row[name] = records[1][i]
+	}
+	want := map[string]string{
+		"id":          "class-12",
+		"available":   "true",
+		"name":        offers[0].Name,
+		"category":    "Классы",
+		"price":       "700",
+		"currency":    "RUB",
+		"params":      "Длительность: 90 мин; Уровень: начальный",
+		"pictures":    "https://bezpravil.net/img/1.jpg https://bezpravil.net/img/2.jpg",
+		"description": "Первая строка\nВторая строка",
+	}
+	for name, value := range want {
+		if row[name] != value {
+			t.Errorf("%s = %q, want %q", name, row[name], value)
+		}
+	}
+	if records[2][0] != "pass-1" || len(records[2]) != len(csvHeader) {
+		t.Errorf("Unexpected pass row: %q", records[2])
+	}
+}
+
+func TestWriteCsvDelimiter(t *testing.T) {
+	oldDelimiter := config.CsvDelimiter
+	t.Cleanup(func() { config.CsvDelimiter = oldDelimiter })
+	config.CsvDelimiter = ','
+
+	var buf bytes.Buffer
+	if err := writeCsv(&buf, []entity.Offer{{Kind: entity.OfferKindPass, ID: 1, Name: "Пробное; разовое"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), utf8BOM+"id,kind,available,") || !strings.Contains(buf.String(), ",Пробное; разовое,") {
+		t.Errorf("Expected a comma-separated file with CSV_DELIMITER=,:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,151 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+	"yandex-export/config"
+	"yandex-export/entity"
+	"yandex-export/repository"
+)
+
+// feed — предложения и версия каталога, общие для всех выгрузок
+type feed struct {
+	Offers  []entity.Offer
+	Version entity.Version
+}
+
+// loadFeed применяет параметры запроса, тянет предложения из БД и обновляет версию каталога.
+// Версия общая для всех форматов: она меняется, только когда меняются сами данные.
+// Если у клиента уже есть эта версия в формате format (If-None-Match), отвечает 304
+// и возвращает false; при ошибке тоже отвечает сам и возвращает false.
+func loadFeed(w http.ResponseWriter, sr *http.Request, format string) (feed, bool) {
+	applyLinkParams(sr)
+
+	classes, err := repository.FetchClasses()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("fetchClasses error: %v", err), http.StatusInternalServerError)
+		return feed{}, false
+	}
+
+	passes, err := repository.FetchPasses()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("fetchPasses error: %v", err), http.StatusInternalServerError)
+		return feed{}, false
+	}
+
+	offers := make([]entity.Offer, 0, len(classes)+len(passes))
+	offers = append(offers, classes...)
+	offers = append(offers, passes...)
+
+	version, ok := checkVersion(w, sr, HashOffers(offers, picturesVersion()), format)
+	if !ok {
+		return feed{}, false
+	}
+	return feed{Offers: offers, Version: version}, true
+}
+
+// eachOffer передаёт fn сначала классы, затем абонементы — в том же порядке, что и loadFeed
+func eachOffer(pictures bool, fn func(entity.Offer) error) error {
+	if err := repository.EachClass(pictures, fn); err != nil {
+		return fmt.Errorf("fetchClasses error: %w", err)
+	}
+	if err := repository.EachPass(pictures, fn); err != nil {
+		return fmt.Errorf("fetchPasses error: %w", err)
+	}
+	return nil
+}
+
+// applyLinkParams подставляет ссылки из ?passlink= и ?classlink= вместо ссылок по умолчанию
+func applyLinkParams(sr *http.Request) {
+	var passLink, classLink string
+	params := sr.URL.Query()
+	if len(params) > 0 {
+		passLink = params.Get("passlink")
+		classLink = params.Get("classlink")
+	}
+
+	if passLink != "" {
+		config.PassDefaultLink = passLink
+	}
+
+	if classLink != "" {
+		config.ClassDefaultLink = classLink
+	}
+
+	log.Println("passLink:", passLink)
+	log.Println("classLink:", classLink)
+}
+
+// picturesVersion — отпечаток картинок менеджера, если он инициализирован
+func picturesVersion() string {
+	if imageManager := repository.Images(); imageManager != nil {
+		return imageManager.Fingerprint()
+	}
+	return ""
+}
+
+// checkVersion обновляет версию каталога по хешу hash и ставит ETag формата format.
+// Если у клиента уже есть эта версия, отвечает 304 и возвращает false.
+func checkVersion(w http.ResponseWriter, sr *http.Request, hash string, format string) (entity.Version, bool) {
+	mu.Lock()
+	if currentVersion.Hash != hash {
+		currentVersion.Modified = time.Now()
+		currentVersion.PubDate = currentVersion.Modified.Format("2006-01-02T15:04-07:00")
+		currentVersion.Hash = hash
+		log.Println("Updating version: " + currentVersion.PubDate)
+	}
+	version := currentVersion
+	mu.Unlock()
+
+	// Кешируем на стороне клиента: ETag различается по формату, дата изменения общая
+	etag := fmt.Sprintf(`"%s-%s"`, version.Hash[:16], format)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", version.Modified.UTC().Format(http.TimeFormat))
+	if match := sr.Header.Get("If-None-Match"); match != "" && match == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return entity.Version{}, false
+	}
+	return version, true
+}
+
+// studiosVersion возвращает короткий хеш студий и настроек settings.
+// Версия каталога покрывает только предложения, поэтому выгрузки с адресами
+// студий добавляют его к формату: так ETag меняется вместе с адресом.
+func studiosVersion(studios []entity.Studio, settings ...string) string {
+	sorted := append([]entity.Studio(nil), studios...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	hasher := sha256.New()
+	for _, s := range sorted {
+		fmt.Fprintf(hasher, "%d|%s|%s|", s.ID, s.Title, s.Address)
+	}
+	for _, setting := range settings {
+		fmt.Fprintf(hasher, "%s|", setting)
+	}
+	return hex.EncodeToString(hasher.Sum(nil))[:8]
+}
+
+// categoryName возвращает название категории из config.Categories
+func categoryName(categoryID int) string {
+	for _, c := range config.Categories.Category {
+		if c.ID == categoryID {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// isoCurrency переводит код валюты YML в ISO 4217: Яндекс исторически использует RUR
+func isoCurrency(currencyID string) string {
+	if currencyID == "RUR" {
+		return "RUB"
+	}
+	return currencyID
+}
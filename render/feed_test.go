@@ -0,0 +1,30 @@
+package render
+
+import (
+	"testing"
+	"yandex-export/entity"
+)
+
+func TestStudiosVersion(t *testing.T) {
+	studios := []entity.Studio{
+		{ID: 2, Title: "Центр", Address: "ул. Ленина, 1"},
+		{ID: 3, Title: "Север", Address: "пр. Мира, 10"},
+	}
+	version := studiosVersion(studios, "Танцы")
+
+	reordered := []entity.Studio{studios[1], studios[0]}
+	if got := studiosVersion(reordered, "Танцы"); got != version {
+		t.Errorf("Expected the order of studios not to matter, got %s and %s", version, got)
+	}
+	if studios[0].ID != 2 {
+		t.Errorf("Expected the studios slice to stay untouched, got %+v", studios)
+	}
+
+	moved := []entity.Studio{{ID: 2, Title: "Центр", Address: "ул. Садовая, 5"}, studios[1]}
+	if studiosVersion(moved, "Танцы") == version {
+		t.Errorf("Expected a new address to change the version")
+	}
+	if studiosVersion(studios, "Фитнес") == version {
+		t.Errorf("Expected a new setting to change the version")
+	}
+}
@@ -0,0 +1,94 @@
+package render
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+// Google Merchant Center допускает до 10 дополнительных картинок
+const googleMaxAdditionalImages = 10
+
+type googleRss struct {
+	XMLName xml.Name      `xml:"rss"`
+	Version string        `xml:"version,attr"`
+	NS      string        `xml:"xmlns:g,attr"`
+	Channel googleChannel `xml:"channel"`
+}
+
+type googleChannel struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link"`
+	Description string       `xml:"description"`
+	Items       []googleItem `xml:"item"`
+}
+
+type googleItem struct {
+	ID                   string   `xml:"g:id"`
+	Title                string   `xml:"g:title"`
+	Description          string   `xml:"g:description"`
+	Link                 string   `xml:"g:link"`
+	ImageLink            string   `xml:"g:image_link,omitempty"`
+	AdditionalImageLinks []string `xml:"g:additional_image_link,omitempty"`
+	Availability         string   `xml:"g:availability"`
+	Price                string   `xml:"g:price"`
+	Condition            string   `xml:"g:condition"`
+	Brand                string   `xml:"g:brand"`
+	ProductType          string   `xml:"g:product_type,omitempty"`
+	IdentifierExists     string   `xml:"g:identifier_exists"`
+}
+
+// GoogleHandler отдаёт те же предложения, что и XmlHandler, в формате
+// товарного фида Google Merchant Center (RSS 2.0)
+func GoogleHandler(w http.ResponseWriter, sr *http.Request) {
+	compact := compactOutput(sr)
+	feed, ok := loadFeed(w, sr, xmlFormat("google", compact))
+	if !ok {
+		return
+	}
+
+	rss := googleRss{
+		Version: "2.0",
+		NS:      "http://base.google.com/ns/1.0",
+		Channel: googleChannel{
+			Title:       config.CompanyName,
+			Link:        config.ShopURL,
+			Description: config.CompanyName,
+			Items:       make([]googleItem, 0, len(feed.Offers)),
+		},
+	}
+	for _, o := range feed.Offers {
+		rss.Channel.Items = append(rss.Channel.Items, googleItemFromOffer(o))
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	if err := writeXML(w, rss, !compact); err != nil {
+		log.Printf("Ошибка записи XML для Google: %v", err)
+	}
+}
+
+func googleItemFromOffer(o entity.Offer) googleItem {
+	item := googleItem{
+		ID:               o.UID(),
+		Title:            o.Name,
+		Description:      o.Description,
+		Link:             o.URL,
+		Availability:     "in_stock",
+		Price:            fmt.Sprintf("%d.00 %s", o.Price, isoCurrency(o.CurrencyID)),
+		Condition:        "new",
+		Brand:            o.Vendor,
+		ProductType:      categoryName(o.CategoryID),
+		IdentifierExists: "no",
+	}
+	if !o.IsAvailable() {
+		item.Availability = "out_of_stock"
+	}
+	if len(o.Pictures) > 0 {
+		item.ImageLink = o.Pictures[0]
+		item.AdditionalImageLinks = o.Pictures[1:min(len(o.Pictures), googleMaxAdditionalImages+1)]
+	}
+	return item
+}
@@ -0,0 +1,76 @@
+package render
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+func TestGoogleItemFromOffer(t *testing.T) {
+	oldCategories := config.Categories
+	t.Cleanup(func() { config.Categories = oldCategories })
+	config.Categories = entity.Categories{Category: []entity.Category{{ID: 1, Name: "Классы"}}}
+
+	pictures := make([]string, 0, 13)
+	for i := 0; i < cap(pictures); i++ {
+		pictures = append(pictures, fmt.Sprintf("https://bezpravil.net/img/%d.jpg", i))
+	}
+	o := entity.Offer{
+		Kind:        entity.OfferKindClass,
+		ID:          12,
+		Name:        "Хип-хоп в студии Центр",
+		Description: "Для начинающих",
+		URL:         "https://bezpravil.net",
+		Vendor:      "Без правил",
+		Price:       700,
+		CurrencyID:  "RUR",
+		CategoryID:  1,
+		Pictures:    pictures,
+	}
+
+	item := googleItemFromOffer(o)
+	output, err := xml.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(output)
+	// Обязательные поля Merchant Center
+	for _, want := range []string{
+		"<g:id>class-12</g:id>",
+		"<g:title>Хип-хоп в студии Центр</g:title>",
+		"<g:description>Для начинающих</g:description>",
+		"<g:link>https://bezpravil.net</g:link>",
+		"<g:image_link>https://bezpravil.net/img/0.jpg</g:image_link>",
+		"<g:availability>in_stock</g:availability>",
+		"<g:price>700.00 RUB</g:price>",
+		"<g:condition>new</g:condition>",
+		"<g:brand>Без правил</g:brand>",
+		"<g:product_type>Классы</g:product_type>",
+		"<g:identifier_exists>no</g:identifier_exists>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("item does not contain %s:\n%s", want, got)
+		}
+	}
+	if len(item.AdditionalImageLinks) != googleMaxAdditionalImages || item.AdditionalImageLinks[0] != pictures[1] {
+		t.Errorf("Expected %d additional images after the main one, got %v", googleMaxAdditionalImages, item.AdditionalImageLinks)
+	}
+
+	full := false
+	o.Available = &full
+	o.Pictures = nil
+	item = googleItemFromOffer(o)
+	if item.Availability != "out_of_stock" {
+		t.Errorf("Expected out_of_stock for a full class, got %q", item.Availability)
+	}
+	output, err = xml.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(output), "image_link") {
+		t.Errorf("Expected no image links without pictures:\n%s", output)
+	}
+}
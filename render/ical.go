@@ -0,0 +1,235 @@
+package render
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"yandex-export/common"
+	"yandex-export/config"
+	"yandex-export/entity"
+	"yandex-export/repository"
+	"yandex-export/schedule"
+)
+
+// icalDefaultDuration — длительность события, если у занятия она неизвестна
+const icalDefaultDuration = time.Hour
+
+// icalTimezoneYears — на сколько лет вперёд описываются переходы часового пояса.
+// Календарь меняется каждую неделю, и окно сдвигается вместе с ним.
+const icalTimezoneYears = 3
+
+var icalDays = [...]string{
+	entity.Monday:    "MO",
+	entity.Tuesday:   "TU",
+	entity.Wednesday: "WE",
+	entity.Thursday:  "TH",
+	entity.Friday:    "FR",
+	entity.Saturday:  "SA",
+	entity.Sunday:    "SU",
+}
+
+// ICalHandler отдаёт расписание классов как календарь iCalendar:
+// по повторяющемуся еженедельно событию на каждый класс и время занятия.
+// ?studio= и ?style= оставляют только классы студии (ID или название)
+// и стиля (ID, название или slug).
+func ICalHandler(w http.ResponseWriter, sr *http.Request) {
+	// Адреса студий попадают в LOCATION, поэтому входят в ETag
+	studios, err := repository.FetchStudios()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("fetchStudios error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	studioFilter := sr.URL.Query().Get("studio")
+	styleFilter := sr.URL.Query().Get("style")
+	// Классы без даты старта повторяются с начала текущей недели,
+	// поэтому календарь меняется раз в неделю — это видно и по ETag
+	week := weekStart(time.Now().In(config.Location))
+	format := "ics-" + week.Format("20060102") + "-" + studiosVersion(studios) + "-" +
+		common.Slugify(studioFilter) + "-" + common.Slugify(styleFilter)
+	feed, ok := loadFeed(w, sr, format)
+	if !ok {
+		return
+	}
+
+	addresses := make(map[int]string, len(studios))
+	for _, s := range studios {
+		addresses[s.ID] = s.Address
+	}
+
+	cal := &icalWriter{}
+	cal.line("BEGIN:VCALENDAR")
+	cal.line("VERSION:2.0")
+	cal.line("PRODID:-//" + config.CompanyName + "//Расписание//RU")
+	cal.line("CALSCALE:GREGORIAN")
+	cal.line("METHOD:PUBLISH")
+	cal.text("X-WR-CALNAME", "Расписание — "+config.CompanyName)
+	cal.line("X-WR-TIMEZONE:" + config.Location.String())
+	cal.timezone(config.Location, week.AddDate(-1, 0, 0), week.AddDate(icalTimezoneYears, 0, 0))
+	for _, o := range feed.Offers {
+		if o.Kind != entity.OfferKindClass || !matchesFilter(o, studioFilter, styleFilter) {
+			continue
+		}
+		for _, session := range o.Schedule.Sessions {
+			cal.event(o, session, addresses[o.Studio.ID], week, feed.Version.Modified)
+		}
+	}
+	cal.line("END:VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Write([]byte(cal.String()))
+}
+
+// matchesFilter проверяет фильтры ?studio= и ?style=; пустой фильтр пропускает всё
+func matchesFilter(o entity.Offer, studio, style string) bool {
+	if studio != "" && studio != strconv.Itoa(o.Studio.ID) && !strings.EqualFold(studio, o.Studio.Title) {
+		return false
+	}
+	if style != "" && style != strconv.Itoa(o.Style.ID) && !strings.EqualFold(style, o.Style.Title) &&
+		common.Slugify(style) != o.Style.Slug {
+		return false
+	}
+	return true
+}
+
+// icalWriter собирает календарь по правилам RFC 5545: строки через CRLF,
+// длинные строки переносятся по 75 байт
+type icalWriter struct {
+	b strings.Builder
+}
+
+func (c *icalWriter) String() string {
+	return c.b.String()
+}
+
+func (c *icalWriter) line(s string) {
+	for len(s) > 75 {
+		cut := 75
+		for !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		c.b.WriteString(s[:cut] + "\r\n")
+		// Строка продолжения начинается с пробела, он занимает один байт из 75
+		s = " " + s[cut:]
+	}
+	c.b.WriteString(s + "\r\n")
+}
+
+// text пишет текстовое свойство, экранируя спецсимволы
+func (c *icalWriter) text(name, value string) {
+	value = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(value)
+	c.line(name + ":" + value)
+}
+
+// timezone описывает часовой пояс его настоящими переходами между from и to:
+// каждый период — отдельный блок STANDARD или DAYLIGHT без RRULE.
+// Для поясов без перехода на летнее время (как Москва) получается один блок.
+func (c *icalWriter) timezone(loc *time.Location, from, to time.Time) {
+	c.line("BEGIN:VTIMEZONE")
+	c.line("TZID:" + loc.String())
+	at := from.In(loc)
+	for {
+		onset, end := at.ZoneBounds()
+		_, offsetFrom := at.Zone()
+		if !onset.IsZero() {
+			_, offsetFrom = onset.Add(-time.Second).In(loc).Zone()
+		}
+		c.observance(at, onset, offsetFrom)
+		if end.IsZero() || !end.Before(to) {
+			break
+		}
+		at = end.In(loc)
+	}
+	c.line("END:VTIMEZONE")
+}
+
+// observance пишет период часового пояса, в который попадает at.
+// DTSTART — местное время начала периода по прежнему смещению offsetFrom.
+func (c *icalWriter) observance(at, onset time.Time, offsetFrom int) {
+	name, offset := at.Zone()
+	kind := "STANDARD"
+	if at.IsDST() {
+		kind = "DAYLIGHT"
+	}
+	start := "19700101T000000"
+	if !onset.IsZero() {
+		start = onset.In(time.FixedZone("", offsetFrom)).Format("20060102T150405")
+	}
+
+	c.line("BEGIN:" + kind)
+	c.line("DTSTART:" + start)
+	c.line("TZOFFSETFROM:" + icalOffset(offsetFrom))
+	c.line("TZOFFSETTO:" + icalOffset(offset))
+	c.line("TZNAME:" + name)
+	c.line("END:" + kind)
+}
+
+// icalOffset форматирует смещение от UTC в секундах как +ЧЧММ
+func icalOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset%3600/60)
+}
+
+// event пишет событие, повторяющееся каждую неделю с даты старта класса
+// (или с начала недели week, если она неизвестна) до даты окончания.
+// stamp — время изменения каталога, оно попадает в DTSTAMP.
+func (c *icalWriter) event(o entity.Offer, session entity.Session, address string, week, stamp time.Time) {
+	from := o.StartDate
+	if from.IsZero() {
+		from = week
+	}
+	// Первое занятие в этот день недели, начиная с from
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, config.Location)
+	day = day.AddDate(0, 0, (int(session.Weekday)-int(schedule.WeekdayOf(day))+7)%7)
+	start := day.Add(time.Duration(session.Start) * time.Minute)
+	duration := session.Duration
+	if duration <= 0 {
+		duration = icalDefaultDuration
+	}
+
+	rrule := "RRULE:FREQ=WEEKLY;BYDAY=" + icalDays[session.Weekday]
+	if !o.EndDate.IsZero() {
+		until := time.Date(o.EndDate.Year(), o.EndDate.Month(), o.EndDate.Day(), 23, 59, 59, 0, config.Location)
+		rrule += ";UNTIL=" + until.UTC().Format("20060102T150405Z")
+	}
+
+	location := o.Studio.Title
+	if address != "" {
+		location = strings.TrimSpace(location + ", " + address)
+	}
+
+	c.line("BEGIN:VEVENT")
+	c.line(fmt.Sprintf("UID:%s-%s-%s@%s", o.UID(), icalDays[session.Weekday], strings.ReplaceAll(session.Start.String(), ":", ""), icalHost()))
+	c.line("DTSTAMP:" + stamp.UTC().Format("20060102T150405Z"))
+	c.line("DTSTART;TZID=" + config.Location.String() + ":" + start.Format("20060102T150405"))
+	c.line("DTEND;TZID=" + config.Location.String() + ":" + start.Add(duration).Format("20060102T150405"))
+	c.line(rrule)
+	c.text("SUMMARY", o.Name)
+	c.text("DESCRIPTION", o.Description)
+	if location != "" {
+		c.text("LOCATION", location)
+	}
+	if o.URL != "" {
+		c.line("URL:" + o.URL)
+	}
+	c.line("END:VEVENT")
+}
+
+// weekStart возвращает полночь понедельника недели, в которую попадает t
+func weekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return day.AddDate(0, 0, -int(schedule.WeekdayOf(day)))
+}
+
+// icalHost — домен для UID событий
+func icalHost() string {
+	host := strings.TrimPrefix(strings.TrimPrefix(config.ShopURL, "https://"), "http://")
+	return strings.TrimRight(host, "/")
+}
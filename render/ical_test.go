@@ -0,0 +1,160 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+func TestICalEvent(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldLocation := config.Location
+	t.Cleanup(func() { config.Location = oldLocation })
+	config.Location = loc
+
+	o := entity.Offer{
+		Kind:        entity.OfferKindClass,
+		ID:          12,
+		Name:        "Хип-хоп в студии Центр",
+		Description: "Для начинающих; с нуля, без подготовки\nВторая строка",
+		Studio:      entity.Studio{ID: 3, Title: "Центр"},
+		// Старт в пятницу, занятия по средам: первое — 9 сентября
+		StartDate: time.Date(2026, 9, 4, 0, 0, 0, 0, loc),
+		EndDate:   time.Date(2026, 12, 30, 0, 0, 0, 0, loc),
+	}
+	session := entity.Session{Weekday: entity.Wednesday, Start: 19 * 60, Duration: 90 * time.Minute}
+
+	var cal icalWriter
+	week := time.Date(2026, 9, 28, 0, 0, 0, 0, loc)
+	cal.event(o, session, "ул. Ленина, 1", week, time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	got := cal.String()
+
+	for _, want := range []string{
+		"UID:class-12-WE-1900@",
+		"DTSTART;TZID=Europe/Moscow:20260909T190000\r\n",
+		"DTEND;TZID=Europe/Moscow:20260909T203000\r\n",
+		"RRULE:FREQ=WEEKLY;BYDAY=WE;UNTIL=20261230T205959Z\r\n",
+		`DESCRIPTION:Для начинающих\; с нуля\, без подготовки\nВторая строка`,
+		`LOCATION:Центр\, ул. Ленина\, 1`,
+	} {
+		if !strings.Contains(unfold(got), want) {
+			t.Errorf("event does not contain %q:\n%s", want, got)
+		}
+	}
+	for _, line := range strings.Split(got, "\r\n") {
+		if len(line) > 75 || !utf8.ValidString(line) {
+			t.Errorf("badly folded line %q", line)
+		}
+	}
+}
+
+func TestICalEventWithoutStartDate(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldLocation := config.Location
+	t.Cleanup(func() { config.Location = oldLocation })
+	config.Location = loc
+
+	o := entity.Offer{Kind: entity.OfferKindClass, ID: 12, Name: "Хип-хоп"}
+	session := entity.Session{Weekday: entity.Saturday, Start: 12 * 60}
+
+	// Четверг, 1 октября: неделя начинается в понедельник, 28 сентября
+	week := weekStart(time.Date(2026, 10, 1, 15, 30, 0, 0, loc))
+	if want := time.Date(2026, 9, 28, 0, 0, 0, 0, loc); !week.Equal(want) {
+		t.Fatalf("weekStart = %v, want %v", week, want)
+	}
+
+	// Новая версия каталога на той же неделе не сдвигает начало событий
+	var first, second icalWriter
+	first.event(o, session, "", week, time.Date(2026, 9, 29, 9, 0, 0, 0, loc))
+	second.event(o, session, "", week, time.Date(2026, 10, 2, 18, 0, 0, 0, loc))
+	for _, cal := range []icalWriter{first, second} {
+		if !strings.Contains(cal.String(), "DTSTART;TZID=Europe/Moscow:20261003T120000\r\n") {
+			t.Errorf("expected the first Saturday of the week:\n%s", cal.String())
+		}
+	}
+}
+
+func TestICalTimezone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cal icalWriter
+	cal.timezone(berlin, time.Date(2026, 1, 1, 0, 0, 0, 0, berlin), time.Date(2027, 1, 1, 0, 0, 0, 0, berlin))
+	want := strings.Join([]string{
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Berlin",
+		"BEGIN:STANDARD",
+		"DTSTART:20251026T030000",
+		"TZOFFSETFROM:+0200",
+		"TZOFFSETTO:+0100",
+		"TZNAME:CET",
+		"END:STANDARD",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:20260329T020000",
+		"TZOFFSETFROM:+0100",
+		"TZOFFSETTO:+0200",
+		"TZNAME:CEST",
+		"END:DAYLIGHT",
+		"BEGIN:STANDARD",
+		"DTSTART:20261025T030000",
+		"TZOFFSETFROM:+0200",
+		"TZOFFSETTO:+0100",
+		"TZNAME:CET",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+	}, "\r\n") + "\r\n"
+	if got := cal.String(); got != want {
+		t.Errorf("timezone:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Без летнего времени остаётся один период
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal = icalWriter{}
+	cal.timezone(moscow, time.Date(2026, 1, 1, 0, 0, 0, 0, moscow), time.Date(2029, 1, 1, 0, 0, 0, 0, moscow))
+	if got := cal.String(); strings.Count(got, "BEGIN:STANDARD") != 1 || strings.Contains(got, "DAYLIGHT") ||
+		!strings.Contains(got, "TZOFFSETTO:+0300\r\n") {
+		t.Errorf("Expected a single +0300 period for Moscow:\n%s", got)
+	}
+}
+
+func TestMatchesFilter(t *testing.T) {
+	o := entity.Offer{
+		Studio: entity.Studio{ID: 3, Title: "Центр"},
+		Style:  entity.Style{ID: 5, Title: "Хип-хоп", Slug: "hip-hop"},
+	}
+	tests := []struct {
+		studio, style string
+		want          bool
+	}{
+		{"", "", true},
+		{"3", "", true},
+		{"центр", "", true},
+		{"4", "", false},
+		{"", "5", true},
+		{"", "hip-hop", true},
+		{"", "Хип-хоп", true},
+		{"3", "jazz-funk", false},
+	}
+	for _, tt := range tests {
+		if got := matchesFilter(o, tt.studio, tt.style); got != tt.want {
+			t.Errorf("matchesFilter(%q, %q) = %v, want %v", tt.studio, tt.style, got, tt.want)
+		}
+	}
+}
+
+func unfold(s string) string {
+	return strings.ReplaceAll(s, "\r\n ", "")
+}
@@ -0,0 +1,107 @@
+package render
+
+import (
+	"encoding/json"
+	"net/http"
+	"yandex-export/config"
+	"yandex-export/entity"
+	"yandex-export/schedule"
+)
+
+type jsonCatalog struct {
+	Date       string            `json:"date"`
+	Company    string            `json:"company"`
+	Categories []entity.Category `json:"categories"`
+	Offers     []jsonOffer       `json:"offers"`
+}
+
+type jsonOffer struct {
+	ID               string           `json:"id"`
+	OfferID          int              `json:"offer_id"`
+	Kind             string           `json:"kind"`
+	Available        bool             `json:"available"`
+	Name             string           `json:"name"`
+	Vendor           string           `json:"vendor"`
+	Price            int              `json:"price"`
+	Currency         string           `json:"currency"`
+	CategoryID       int              `json:"category_id"`
+	Category         string           `json:"category"`
+	URL              string           `json:"url"`
+	Pictures         []string         `json:"pictures"`
+	Description      string           `json:"description"`
+	ShortDescription string           `json:"short_description,omitempty"`
+	Params           []entity.Param   `json:"params"`
+	Schedule         *entity.Schedule `json:"schedule,omitempty"`
+	ScheduleText     string           `json:"schedule_text,omitempty"`
+	Studio           string           `json:"studio,omitempty"`
+	Style            string           `json:"style,omitempty"`
+	Teachers         []string         `json:"teachers,omitempty"`
+	StartDate        string           `json:"start_date,omitempty"`
+	EndDate          string           `json:"end_date,omitempty"`
+}
+
+// JsonHandler отдаёт те же предложения, что и YML, в JSON.
+// Параметры и расписание остаются структурированными полями.
+func JsonHandler(w http.ResponseWriter, sr *http.Request) {
+	feed, ok := loadFeed(w, sr, "json")
+	if !ok {
+		return
+	}
+
+	catalog := jsonCatalog{
+		Date:       feed.Version.PubDate,
+		Company:    config.CompanyName,
+		Categories: config.Categories.Category,
+		Offers:     make([]jsonOffer, 0, len(feed.Offers)),
+	}
+	for _, o := range feed.Offers {
+		catalog.Offers = append(catalog.Offers, jsonOfferFrom(o))
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(catalog)
+}
+
+func jsonOfferFrom(o entity.Offer) jsonOffer {
+	out := jsonOffer{
+		ID:               o.UID(),
+		OfferID:          o.ID,
+		Kind:             o.Kind,
+		Available:        o.IsAvailable(),
+		Name:             o.Name,
+		Vendor:           o.Vendor,
+		Price:            o.Price,
+		Currency:         isoCurrency(o.CurrencyID),
+		CategoryID:       o.CategoryID,
+		Category:         categoryName(o.CategoryID),
+		URL:              o.URL,
+		Pictures:         o.Pictures,
+		Description:      o.Description,
+		ShortDescription: o.ShortDescription,
+		Params:           o.Params,
+		Studio:           o.Studio.Title,
+		Style:            o.Style.Title,
+	}
+	if out.Pictures == nil {
+		out.Pictures = []string{}
+	}
+	if out.Params == nil {
+		out.Params = []entity.Param{}
+	}
+	if !o.Schedule.IsEmpty() {
+		out.Schedule = &o.Schedule
+		out.ScheduleText = schedule.Text(o.Schedule)
+	}
+	for _, t := range o.Teachers {
+		out.Teachers = append(out.Teachers, t.Name)
+	}
+	if !o.StartDate.IsZero() {
+		out.StartDate = o.StartDate.Format("2006-01-02")
+	}
+	if !o.EndDate.IsZero() {
+		out.EndDate = o.EndDate.Format("2006-01-02")
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package render
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+func TestJsonOfferFrom(t *testing.T) {
+	setCategoriesConfig(t)
+
+	full := false
+	o := entity.Offer{
+		Kind:       entity.OfferKindClass,
+		ID:         12,
+		Available:  &full,
+		Name:       "Хип-хоп в студии Центр",
+		Price:      700,
+		CurrencyID: "RUR",
+		CategoryID: 1,
+		Studio:     entity.Studio{ID: 3, Title: "Центр"},
+		Style:      entity.Style{Title: "Хип-хоп"},
+		Teachers:   []entity.Teacher{{Name: "Анна"}, {Name: "Олег"}},
+		Schedule:   entity.Schedule{Sessions: []entity.Session{{Weekday: entity.Monday, Start: 19 * 60}}},
+		StartDate:  time.Date(2026, 9, 4, 0, 0, 0, 0, time.UTC),
+	}
+
+	got := jsonOfferFrom(o)
+	if got.ID != "class-12" || got.OfferID != 12 || got.Available {
+		t.Errorf("Unexpected identity: %+v", got)
+	}
+	if got.Currency != "RUB" || got.Category != "Классы" || got.Studio != "Центр" || got.Style != "Хип-хоп" {
+		t.Errorf("Unexpected fields: %+v", got)
+	}
+	if strings.Join(got.Teachers, ",") != "Анна,Олег" || got.StartDate != "2026-09-04" || got.EndDate != "" {
+		t.Errorf("Unexpected teachers or dates: %+v", got)
+	}
+	if got.Schedule == nil || got.ScheduleText == "" {
+		t.Errorf("Expected a structured schedule with text, got %+v", got)
+	}
+
+	// Пустые списки остаются массивами, а не null
+	output, err := json.Marshal(jsonOfferFrom(entity.Offer{Kind: entity.OfferKindPass, ID: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"id":"pass-1"`, `"available":true`, `"pictures":[]`, `"params":[]`} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("JSON does not contain %s:\n%s", want, output)
+		}
+	}
+	for _, absent := range []string{`"schedule"`, `"teachers"`, `"start_date"`} {
+		if strings.Contains(string(output), absent) {
+			t.Errorf("JSON should omit %s:\n%s", absent, output)
+		}
+	}
+}
+
+// setCategoriesConfig задаёт категории для тестов и возвращает прежние после теста
+func setCategoriesConfig(t *testing.T) {
+	t.Helper()
+	oldCategories := config.Categories
+	t.Cleanup(func() { config.Categories = oldCategories })
+	config.Categories = entity.Categories{Category: []entity.Category{
+		{ID: 1, Name: "Классы"},
+		{ID: 2, Name: "Абонементы"},
+	}}
+}
@@ -0,0 +1,250 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"yandex-export/config"
+	"yandex-export/entity"
+	"yandex-export/repository"
+)
+
+var schemaDays = [...]string{
+	entity.Monday:    "https://schema.org/Monday",
+	entity.Tuesday:   "https://schema.org/Tuesday",
+	entity.Wednesday: "https://schema.org/Wednesday",
+	entity.Thursday:  "https://schema.org/Thursday",
+	entity.Friday:    "https://schema.org/Friday",
+	entity.Saturday:  "https://schema.org/Saturday",
+	entity.Sunday:    "https://schema.org/Sunday",
+}
+
+type ldGraph struct {
+	Context string `json:"@context"`
+	Graph   []any  `json:"@graph"`
+}
+
+type ldOrganization struct {
+	Type string `json:"@type"`
+	ID   string `json:"@id,omitempty"`
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+type ldCourse struct {
+	Type            string             `json:"@type"`
+	ID              string             `json:"@id"`
+	Name            string             `json:"name"`
+	Description     string             `json:"description"`
+	URL             string             `json:"url,omitempty"`
+	Image           []string           `json:"image,omitempty"`
+	About           string             `json:"about,omitempty"`
+	Provider        ldOrganization     `json:"provider"`
+	Offers          ldOffer            `json:"offers"`
+	CourseInstances []ldCourseInstance `json:"hasCourseInstance,omitempty"`
+}
+
+type ldCourseInstance struct {
+	Type           string     `json:"@type"`
+	CourseMode     string     `json:"courseMode"`
+	Location       *ldPlace   `json:"location,omitempty"`
+	Instructor     []ldPerson `json:"instructor,omitempty"`
+	CourseSchedule ldSchedule `json:"courseSchedule"`
+}
+
+type ldPlace struct {
+	Type    string `json:"@type"`
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+}
+
+type ldPerson struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+type ldSchedule struct {
+	Type             string `json:"@type"`
+	RepeatFrequency  string `json:"repeatFrequency"`
+	ByDay            string `json:"byDay"`
+	StartTime        string `json:"startTime"`
+	EndTime          string `json:"endTime,omitempty"`
+	StartDate        string `json:"startDate,omitempty"`
+	EndDate          string `json:"endDate,omitempty"`
+	ScheduleTimezone string `json:"scheduleTimezone,omitempty"`
+}
+
+type ldProduct struct {
+	Type        string         `json:"@type"`
+	ID          string         `json:"@id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Image       []string       `json:"image,omitempty"`
+	Brand       ldOrganization `json:"brand"`
+	Category    string         `json:"category,omitempty"`
+	Offers      ldOffer        `json:"offers"`
+}
+
+type ldOffer struct {
+	Type          string `json:"@type"`
+	Price         string `json:"price"`
+	PriceCurrency string `json:"priceCurrency"`
+	URL           string `json:"url,omitempty"`
+	Availability  string `json:"availability"`
+	Category      string `json:"category,omitempty"`
+}
+
+var jsonLdSnippet = template.Must(template.New("jsonld").Parse(
+	`<script type="application/ld+json">{{.}}</script>` + "\n"))
+
+// JsonLdHandler отдаёт предложения как разметку schema.org в JSON-LD:
+// классы — Course с расписанием в CourseInstance, абонементы — Product с Offer.
+// С параметром ?snippet=1 ответ обёрнут в <script>, чтобы сайт мог вставить его как есть.
+func JsonLdHandler(w http.ResponseWriter, sr *http.Request) {
+	// Адреса студий попадают в Place.address, поэтому входят в ETag
+	studios, err := repository.FetchStudios()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("fetchStudios error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	snippet := sr.URL.Query().Get("snippet") != ""
+	format := "jsonld-" + studiosVersion(studios)
+	if snippet {
+		format += "-snippet"
+	}
+	feed, ok := loadFeed(w, sr, format)
+	if !ok {
+		return
+	}
+
+	places := make(map[int]entity.Studio, len(studios))
+	for _, s := range studios {
+		places[s.ID] = s
+	}
+
+	output, err := json.MarshalIndent(ldGraphFrom(feed.Offers, places), "", "  ")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("JSON marshal error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if snippet {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		writeJsonLdSnippet(w, output)
+		return
+	}
+	w.Header().Set("Content-Type", "application/ld+json; charset=utf-8")
+	w.Write(output)
+}
+
+// ldGraphFrom собирает граф: школа, затем курсы и абонементы в порядке предложений
+func ldGraphFrom(offers []entity.Offer, places map[int]entity.Studio) ldGraph {
+	graph := ldGraph{Context: "https://schema.org", Graph: []any{ldSchool()}}
+	for _, o := range offers {
+		if o.Kind == entity.OfferKindClass {
+			graph.Graph = append(graph.Graph, ldCourseFrom(o, places))
+		} else {
+			graph.Graph = append(graph.Graph, ldProductFrom(o))
+		}
+	}
+	return graph
+}
+
+// writeJsonLdSnippet оборачивает JSON в <script>. template.JS отключает
+// экранирование html/template, поэтому безопасность держится на encoding/json:
+// он заменяет <, > и & на \u003c, \u003e и \u0026, и строка «</script>»
+// из названия не может закрыть тег раньше времени.
+func writeJsonLdSnippet(w io.Writer, output []byte) error {
+	return jsonLdSnippet.Execute(w, template.JS(output))
+}
+
+// ldSchool — сама школа; курсы и абонементы ссылаются на неё по @id
+func ldSchool() ldOrganization {
+	return ldOrganization{
+		Type: "DanceGroup",
+		ID:   config.ShopURL + "#organization",
+		Name: config.CompanyName,
+		URL:  config.ShopURL,
+	}
+}
+
+func ldOfferFrom(o entity.Offer) ldOffer {
+	availability := "https://schema.org/InStock"
+	if !o.IsAvailable() {
+		availability = "https://schema.org/SoldOut"
+	}
+	return ldOffer{
+		Type:          "Offer",
+		Price:         fmt.Sprintf("%d.00", o.Price),
+		PriceCurrency: isoCurrency(o.CurrencyID),
+		URL:           o.URL,
+		Availability:  availability,
+		Category:      categoryName(o.CategoryID),
+	}
+}
+
+func ldCourseFrom(o entity.Offer, places map[int]entity.Studio) ldCourse {
+	course := ldCourse{
+		Type:        "Course",
+		ID:          config.ShopURL + "#" + o.UID(),
+		Name:        o.Name,
+		Description: o.Description,
+		URL:         o.URL,
+		Image:       o.Pictures,
+		About:       o.Style.Title,
+		Provider:    ldOrganization{Type: "DanceGroup", ID: config.ShopURL + "#organization"},
+		Offers:      ldOfferFrom(o),
+	}
+
+	var location *ldPlace
+	if o.Studio.ID != 0 {
+		location = &ldPlace{Type: "Place", Name: o.Studio.Title, Address: places[o.Studio.ID].Address}
+	}
+	var instructors []ldPerson
+	for _, t := range o.Teachers {
+		instructors = append(instructors, ldPerson{Type: "Person", Name: t.Name})
+	}
+
+	for _, session := range o.Schedule.Sessions {
+		schedule := ldSchedule{
+			Type:             "Schedule",
+			RepeatFrequency:  "P1W",
+			ByDay:            schemaDays[session.Weekday],
+			StartTime:        session.Start.String(),
+			ScheduleTimezone: config.Location.String(),
+		}
+		if end, ok := session.End(); ok {
+			schedule.EndTime = end.String()
+		}
+		if !o.StartDate.IsZero() {
+			schedule.StartDate = o.StartDate.Format("2006-01-02")
+		}
+		if !o.EndDate.IsZero() {
+			schedule.EndDate = o.EndDate.Format("2006-01-02")
+		}
+		course.CourseInstances = append(course.CourseInstances, ldCourseInstance{
+			Type:           "CourseInstance",
+			CourseMode:     "onsite",
+			Location:       location,
+			Instructor:     instructors,
+			CourseSchedule: schedule,
+		})
+	}
+	return course
+}
+
+func ldProductFrom(o entity.Offer) ldProduct {
+	return ldProduct{
+		Type:        "Product",
+		ID:          config.ShopURL + "#" + o.UID(),
+		Name:        o.Name,
+		Description: o.Description,
+		Image:       o.Pictures,
+		Brand:       ldOrganization{Type: "Brand", Name: o.Vendor},
+		Category:    categoryName(o.CategoryID),
+		Offers:      ldOfferFrom(o),
+	}
+}
@@ -0,0 +1,147 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+func TestLdGraphFrom(t *testing.T) {
+	setLdConfig(t)
+
+	offers := []entity.Offer{
+		{
+			Kind:       entity.OfferKindClass,
+			ID:         12,
+			Name:       "Хип-хоп в студии Центр",
+			Price:      700,
+			CurrencyID: "RUR",
+			CategoryID: 1,
+			Studio:     entity.Studio{ID: 3, Title: "Центр"},
+			Style:      entity.Style{Title: "Хип-хоп"},
+			Teachers:   []entity.Teacher{{ID: 5, Name: "Анна"}},
+			Schedule: entity.Schedule{Sessions: []entity.Session{
+				{Weekday: entity.Monday, Start: 19 * 60, Duration: 90 * time.Minute},
+				{Weekday: entity.Wednesday, Start: 19 * 60, Duration: 90 * time.Minute},
+			}},
+		},
+		{
+			Kind:       entity.OfferKindPass,
+			ID:         1,
+			Name:       "Первое пробное занятие",
+			Vendor:     "Без правил",
+			Price:      300,
+			CurrencyID: "RUR",
+			CategoryID: 2,
+		},
+	}
+	places := map[int]entity.Studio{3: {ID: 3, Title: "Центр", Address: "ул. Ленина, 1"}}
+
+	graph := ldGraphFrom(offers, places)
+	if len(graph.Graph) != 3 {
+		t.Fatalf("Expected school, course and product, got %d nodes", len(graph.Graph))
+	}
+	if school, ok := graph.Graph[0].(ldOrganization); !ok || school.ID != "https://bezpravil.net#organization" {
+		t.Errorf("Expected the school first, got %+v", graph.Graph[0])
+	}
+
+	course, ok := graph.Graph[1].(ldCourse)
+	if !ok {
+		t.Fatalf("Expected a Course for the class, got %T", graph.Graph[1])
+	}
+	if course.ID != "https://bezpravil.net#class-12" || course.Provider.ID != "https://bezpravil.net#organization" {
+		t.Errorf("Unexpected course ids: %+v", course)
+	}
+	if course.Offers.Price != "700.00" || course.Offers.PriceCurrency != "RUB" || course.Offers.Category != "Классы" {
+		t.Errorf("Unexpected course offer: %+v", course.Offers)
+	}
+	if len(course.CourseInstances) != 2 {
+		t.Fatalf("Expected a CourseInstance per session, got %d", len(course.CourseInstances))
+	}
+	instance := course.CourseInstances[1]
+	if instance.Location == nil || instance.Location.Name != "Центр" || instance.Location.Address != "ул. Ленина, 1" {
+		t.Errorf("Expected the studio address in location, got %+v", instance.Location)
+	}
+	if len(instance.Instructor) != 1 || instance.Instructor[0].Name != "Анна" {
+		t.Errorf("Unexpected instructors: %+v", instance.Instructor)
+	}
+	want := ldSchedule{
+		Type:             "Schedule",
+		RepeatFrequency:  "P1W",
+		ByDay:            "https://schema.org/Wednesday",
+		StartTime:        "19:00",
+		EndTime:          "20:30",
+		ScheduleTimezone: "Europe/Moscow",
+	}
+	if instance.CourseSchedule != want {
+		t.Errorf("CourseSchedule = %+v, want %+v", instance.CourseSchedule, want)
+	}
+
+	product, ok := graph.Graph[2].(ldProduct)
+	if !ok {
+		t.Fatalf("Expected a Product for the pass, got %T", graph.Graph[2])
+	}
+	if product.ID != "https://bezpravil.net#pass-1" || product.Brand.Name != "Без правил" {
+		t.Errorf("Unexpected product: %+v", product)
+	}
+	if product.Offers.Type != "Offer" || product.Offers.Availability != "https://schema.org/InStock" || product.Category != "Абонементы" {
+		t.Errorf("Unexpected product offer: %+v", product.Offers)
+	}
+}
+
+func TestWriteJsonLdSnippetEscapesScript(t *testing.T) {
+	setLdConfig(t)
+
+	name := `Хип-хоп</script><script>alert("x")</script>`
+	graph := ldGraphFrom([]entity.Offer{{Kind: entity.OfferKindPass, ID: 1, Name: name}}, nil)
+	output, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeJsonLdSnippet(&buf, output); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if strings.Count(got, "</script>") != 1 || !strings.HasSuffix(got, "</script>\n") {
+		t.Fatalf("Name broke out of the snippet:\n%s", got)
+	}
+
+	body := strings.TrimSuffix(strings.TrimPrefix(got, `<script type="application/ld+json">`), "</script>\n")
+	var decoded struct {
+		Graph []struct {
+			Name string `json:"name"`
+		} `json:"@graph"`
+	}
+	if err := json.Unmarshal([]byte(body), &decoded); err != nil {
+		t.Fatalf("Snippet is not valid JSON: %v\n%s", err, body)
+	}
+	if len(decoded.Graph) != 2 || decoded.Graph[1].Name != name {
+		t.Errorf("Expected the name to survive escaping, got %+v", decoded.Graph)
+	}
+}
+
+// setLdConfig задаёт адрес магазина, часовой пояс и категории и возвращает прежние после теста
+func setLdConfig(t *testing.T) {
+	t.Helper()
+	oldURL, oldLocation, oldCategories := config.ShopURL, config.Location, config.Categories
+	t.Cleanup(func() {
+		config.ShopURL, config.Location, config.Categories = oldURL, oldLocation, oldCategories
+	})
+
+	loc, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ShopURL = "https://bezpravil.net"
+	config.Location = loc
+	config.Categories = entity.Categories{Category: []entity.Category{
+		{ID: 1, Name: "Классы"},
+		{ID: 2, Name: "Абонементы"},
+	}}
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"encoding/xml"
+	"io"
+	"yandex-export/entity"
+)
+
+// writeYml кодирует каталог прямо в w, предложение за предложением, не собирая
+// весь XML в один []byte. Результат совпадает с xml.MarshalIndent(catalog, "", "  "),
+// а при indent == false — с xml.Marshal. Порядок элементов повторяет поля
+// entity.YmlCatalog и entity.Shop.
+func writeYml(w io.Writer, catalog entity.YmlCatalog, indent bool) error {
+	yw, err := newYmlWriter(w, catalog, indent)
+	if err != nil {
+		return err
+	}
+	for _, offer := range catalog.Shop.Offers.Offer {
+		if err := yw.offer(offer); err != nil {
+			return err
+		}
+	}
+	return yw.close()
+}
+
+// ymlWriter пишет YML по частям: newYmlWriter — заголовок магазина до <offers>,
+// offer — очередное предложение, close — закрывающие теги. Так предложения
+// можно кодировать по мере чтения из БД, не держа их в памяти (см. XmlHandler).
+type ymlWriter struct {
+	enc                *xml.Encoder
+	root, shop, offers xml.StartElement
+}
+
+// newYmlWriter пишет заголовок каталога; предложения из catalog не пишутся
+func newYmlWriter(w io.Writer, catalog entity.YmlCatalog, indent bool) (*ymlWriter, error) {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, err
+	}
+
+	yw := &ymlWriter{
+		enc: xml.NewEncoder(w),
+		root: xml.StartElement{
+			Name: xml.Name{Local: "yml_catalog"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "date"}, Value: catalog.Date}},
+		},
+		shop:   xml.StartElement{Name: xml.Name{Local: "shop"}},
+		offers: xml.StartElement{Name: xml.Name{Local: "offers"}},
+	}
+	if indent {
+		yw.enc.Indent("", "  ")
+	}
+
+	if err := encodeTokens(yw.enc, yw.root, yw.shop); err != nil {
+		return nil, err
+	}
+	header := []struct {
+		name      string
+		value     any
+		omitEmpty bool
+	}{
+		{"name", catalog.Shop.Name, false},
+		{"company", catalog.Shop.Company, false},
+		{"url", catalog.Shop.URL, false},
+		{"platform", catalog.Shop.Platform, true},
+		{"version", catalog.Shop.Version, true},
+		{"email", catalog.Shop.Email, true},
+		{"currencies", catalog.Shop.Currencies, false},
+		{"categories", catalog.Shop.Categories, false},
+	}
+	for _, field := range header {
+		if field.omitEmpty && field.value == "" {
+			continue
+		}
+		if err := yw.enc.EncodeElement(field.value, xml.StartElement{Name: xml.Name{Local: field.name}}); err != nil {
+			return nil, err
+		}
+	}
+	if err := yw.enc.EncodeToken(yw.offers); err != nil {
+		return nil, err
+	}
+	return yw, nil
+}
+
+func (yw *ymlWriter) offer(offer entity.Offer) error {
+	return yw.enc.Encode(offer)
+}
+
+func (yw *ymlWriter) close() error {
+	if err := encodeTokens(yw.enc, yw.offers.End(), yw.shop.End(), yw.root.End()); err != nil {
+		return err
+	}
+	return yw.enc.Close()
+}
+
+// writeXML кодирует v прямо в w через xml.Encoder, как writeYml — без промежуточного []byte
+func writeXML(w io.Writer, v any, indent bool) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	if indent {
+		enc.Indent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func encodeTokens(enc *xml.Encoder, tokens ...xml.Token) error {
+	for _, t := range tokens {
+		if err := enc.EncodeToken(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"testing"
+	"yandex-export/entity"
+)
+
+func testCatalog(n int) entity.YmlCatalog {
+	offers := make([]entity.Offer, n)
+	for i := range offers {
+		offers[i] = entity.Offer{
+			ID:          i + 1,
+			Vendor:      "Школа танцев «Без правил»",
+			Price:       700,
+			CurrencyID:  "RUR",
+			CategoryID:  1,
+			Pictures:    []string{fmt.Sprintf("https://bezpravil.net/img/1/%d.jpg", i), "https://bezpravil.net/img/1/b.jpg"},
+			URL:         "https://bezpravil.net",
+			Name:        fmt.Sprintf("Хип-хоп & брейк <%d> в студии Центр", i),
+			Description: "Для начинающих\nПо понедельникам и средам в 19:00–20:30",
+			Params: []entity.Param{
+				{Name: "Понедельник", Value: "19:00–20:30"},
+				{Name: "Длительность", Unit: "мин", Value: "90"},
+			},
+		}
+	}
+	return entity.YmlCatalog{
+		Date: "2026-10-19T12:00+03:00",
+		Shop: entity.Shop{
+			Name:       "Без правил",
+			Company:    "Школа танцев «Без правил»",
+			URL:        "https://bezpravil.net",
+			Platform:   "yandex-export",
+			Currencies: &entity.Currencies{Currency: []entity.Currency{{ID: "RUR", Rate: "1"}}},
+			Categories: entity.Categories{Category: []entity.Category{{ID: 1, Name: "Классы"}, {ID: 2, Name: "Абонементы"}}},
+			Offers:     entity.Offers{Offer: offers},
+		},
+	}
+}
+
+func TestWriteYmlMatchesMarshal(t *testing.T) {
+	catalog := testCatalog(3)
+
+	indented, err := xml.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compact, err := xml.Marshal(catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		indent bool
+		want   []byte
+	}{
+		{"indented", true, indented},
+		{"compact", false, compact},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeYml(&buf, catalog, tt.indent); err != nil {
+				t.Fatal(err)
+			}
+			want := xml.Header + string(tt.want)
+			if got := buf.String(); got != want {
+				t.Errorf("writeYml output differs from xml.Marshal:\n got: %s\nwant: %s", got, want)
+			}
+		})
+	}
+}
+
+// Сравнение с прежним способом: MarshalIndent в один буфер и запись целиком.
+// Бенчмарки меряют только кодирование: предложения берутся из памяти, а не из БД.
+func BenchmarkMarshalIndent(b *testing.B) {
+	catalog := testCatalog(5000)
+	b.ReportAllocs()
+	for b.Loop() {
+		output, err := xml.MarshalIndent(catalog, "", "  ")
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.WriteString(io.Discard, xml.Header)
+		io.Discard.Write(output)
+	}
+}
+
+func BenchmarkWriteYml(b *testing.B) {
+	catalog := testCatalog(5000)
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := writeYml(io.Discard, catalog, true); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteYmlCompact(b *testing.B) {
+	catalog := testCatalog(5000)
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := writeYml(io.Discard, catalog, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestYmlWriterByOffer повторяет XmlHandler: заголовок с валютами из первого
+// прохода, затем предложения по одному — и сверяет результат с writeYml
+func TestYmlWriterByOffer(t *testing.T) {
+	catalog := testCatalog(3)
+
+	var summary offerSummary
+	for _, offer := range catalog.Shop.Offers.Offer {
+		summary.add(offer)
+	}
+	header := catalog
+	header.Shop.Offers = entity.Offers{}
+	header.Shop.Currencies = currenciesOf(summary.currencyIDs)
+
+	var got bytes.Buffer
+	yw, err := newYmlWriter(&got, header, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, offer := range catalog.Shop.Offers.Offer {
+		if err := yw.offer(offer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := yw.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var want bytes.Buffer
+	if err := writeYml(&want, catalog, true); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("ymlWriter output differs from writeYml:\n got: %s\nwant: %s", got.String(), want.String())
+	}
+}
+
+func TestWriteXMLMatchesMarshal(t *testing.T) {
+	v := googleItemFromOffer(testCatalog(1).Shop.Offers.Offer[0])
+
+	for _, indent := range []bool{true, false} {
+		var want []byte
+		var err error
+		if indent {
+			want, err = xml.MarshalIndent(v, "", "  ")
+		} else {
+			want, err = xml.Marshal(v)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := writeXML(&buf, v, indent); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != xml.Header+string(want) {
+			t.Errorf("indent=%v: writeXML output differs:\n got: %s\nwant: %s%s", indent, got, xml.Header, want)
+		}
+	}
+}
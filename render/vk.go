@@ -0,0 +1,141 @@
+package render
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+	"yandex-export/config"
+	"yandex-export/entity"
+	"yandex-export/repository"
+)
+
+// Ограничения ВКонтакте строже яндексовых
+const (
+	vkMaxName        = 100
+	vkMinDescription = 10
+	vkMaxDescription = 3000
+	vkMaxPictures    = 5
+	// VK Маркет не принимает фотографии меньше 400×400
+	vkMinImageWidth  = 400
+	vkMinImageHeight = 400
+)
+
+// vkImageSize возвращает размер картинки по её адресу, если его знает менеджер картинок
+var vkImageSize = func(url string) (int, int, bool) {
+	imageManager := repository.Images()
+	if imageManager == nil {
+		return 0, 0, false
+	}
+	return imageManager.ImageSize(url)
+}
+
+// VkHandler отдаёт YML для VK Рекламы и VK Маркета: категории переводятся
+// через config.VkCategories, тексты укорачиваются под лимиты VK,
+// а предложения, которые VK не примет, пропускаются
+func VkHandler(w http.ResponseWriter, sr *http.Request) {
+	compact := compactOutput(sr)
+	feed, ok := loadFeed(w, sr, xmlFormat("vk", compact))
+	if !ok {
+		return
+	}
+
+	offers := make([]entity.Offer, 0, len(feed.Offers))
+	for _, o := range feed.Offers {
+		vkOffer, problems := vkOfferFrom(o)
+		if len(problems) > 0 {
+			log.Printf("VK: пропускаем предложение %s: %s", o.UID(), strings.Join(problems, "; "))
+			continue
+		}
+		offers = append(offers, vkOffer)
+	}
+
+	catalog := ymlCatalog(feed.Version, vkCategories(offers), offers)
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	if err := writeYml(w, catalog, !compact); err != nil {
+		log.Printf("Ошибка записи YML для VK: %v", err)
+	}
+}
+
+// vkOfferFrom приводит предложение к требованиям VK.
+// Непустой список problems означает, что VK такое предложение не примет.
+func vkOfferFrom(o entity.Offer) (entity.Offer, []string) {
+	var problems []string
+
+	o.Name = truncateRunes(o.Name, vkMaxName)
+	o.Description = truncateRunes(o.Description, vkMaxDescription)
+	o.ShortDescription = truncateRunes(o.ShortDescription, vkMaxDescription)
+	if category, ok := config.VkCategories[o.CategoryID]; ok {
+		o.CategoryID = category.ID
+	}
+
+	var pictures []string
+	for _, picture := range o.Pictures {
+		if !strings.HasPrefix(picture, "https://") && !strings.HasPrefix(picture, "http://") {
+			continue
+		}
+		// Размер известен только для картинок из IMAGE_DIR; внешние адреса не проверить
+		if width, height, ok := vkImageSize(picture); ok && (width < vkMinImageWidth || height < vkMinImageHeight) {
+			continue
+		}
+		pictures = append(pictures, picture)
+	}
+	o.Pictures = pictures[:min(len(pictures), vkMaxPictures)]
+
+	if o.Name == "" {
+		problems = append(problems, "нет названия")
+	}
+	if utf8.RuneCountInString(o.Description) < vkMinDescription {
+		problems = append(problems, fmt.Sprintf("описание короче %d символов", vkMinDescription))
+	}
+	if len(o.Pictures) == 0 {
+		problems = append(problems, fmt.Sprintf("нет картинки с абсолютным адресом и размером от %d×%d", vkMinImageWidth, vkMinImageHeight))
+	}
+	if o.Price <= 0 {
+		problems = append(problems, "нет цены")
+	}
+	if o.URL == "" {
+		problems = append(problems, "нет ссылки")
+	}
+	return o, problems
+}
+
+// vkCategories собирает категории VK, на которые ссылаются предложения.
+// Название берётся из VK_CATEGORIES, а если его там нет — из нашей категории.
+func vkCategories(offers []entity.Offer) entity.Categories {
+	names := make(map[int]string)
+	for _, c := range config.Categories.Category {
+		category, ok := config.VkCategories[c.ID]
+		if !ok {
+			category = c
+		}
+		if category.Name == "" {
+			category.Name = c.Name
+		}
+		if _, seen := names[category.ID]; !seen {
+			names[category.ID] = category.Name
+		}
+	}
+
+	var categories entity.Categories
+	used := make(map[int]bool)
+	for _, o := range offers {
+		if used[o.CategoryID] {
+			continue
+		}
+		used[o.CategoryID] = true
+		categories.Category = append(categories.Category, entity.Category{ID: o.CategoryID, Name: names[o.CategoryID]})
+	}
+	return categories
+}
+
+// truncateRunes обрезает строку до limit символов (а не байт, как common.SafelyTruncate)
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
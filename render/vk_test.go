@@ -0,0 +1,85 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+func TestVkOfferFrom(t *testing.T) {
+	oldCategories, oldImageSize := config.VkCategories, vkImageSize
+	t.Cleanup(func() { config.VkCategories, vkImageSize = oldCategories, oldImageSize })
+	config.VkCategories = map[int]entity.Category{1: {ID: 1605, Name: "Спорт и фитнес"}}
+	// Размеры картинок из IMAGE_DIR; внешние адреса менеджеру неизвестны
+	sizes := map[string][2]int{"https://a/small.jpg": {300, 600}, "https://a/1.jpg": {800, 800}}
+	vkImageSize = func(url string) (int, int, bool) {
+		size, ok := sizes[url]
+		return size[0], size[1], ok
+	}
+
+	valid := entity.Offer{
+		Kind:             entity.OfferKindClass,
+		ID:               7,
+		Name:             strings.Repeat("Хип-хоп ", 20),
+		Description:      strings.Repeat("Описание ", 500),
+		ShortDescription: "Кратко",
+		Price:            700,
+		CategoryID:       1,
+		Pictures:         []string{"/img/relative.jpg", "https://a/small.jpg", "https://a/1.jpg", "https://a/2.jpg", "https://a/3.jpg", "https://a/4.jpg", "https://a/5.jpg", "https://a/6.jpg"},
+		URL:              "https://bezpravil.net",
+	}
+
+	o, problems := vkOfferFrom(valid)
+	if len(problems) > 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	if n := utf8.RuneCountInString(o.Name); n > vkMaxName {
+		t.Errorf("name has %d runes, want at most %d", n, vkMaxName)
+	}
+	if n := utf8.RuneCountInString(o.Description); n > vkMaxDescription {
+		t.Errorf("description has %d runes, want at most %d", n, vkMaxDescription)
+	}
+	if o.ShortDescription != "Кратко" {
+		t.Errorf("shortDescription = %q, want it unchanged", o.ShortDescription)
+	}
+	if o.CategoryID != 1605 {
+		t.Errorf("categoryId = %d, want 1605", o.CategoryID)
+	}
+	if len(o.Pictures) != vkMaxPictures || o.Pictures[0] != "https://a/1.jpg" {
+		t.Errorf("pictures = %v", o.Pictures)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(o *entity.Offer)
+	}{
+		{"no pictures", func(o *entity.Offer) { o.Pictures = nil }},
+		{"only relative pictures", func(o *entity.Offer) { o.Pictures = []string{"/img/1.jpg"} }},
+		{"only small pictures", func(o *entity.Offer) { o.Pictures = []string{"https://a/small.jpg"} }},
+		{"short description", func(o *entity.Offer) { o.Description = "Танцы" }},
+		{"no price", func(o *entity.Offer) { o.Price = 0 }},
+		{"no url", func(o *entity.Offer) { o.URL = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := valid
+			tt.modify(&o)
+			if _, problems := vkOfferFrom(o); len(problems) == 0 {
+				t.Error("expected the offer to be rejected")
+			}
+		})
+	}
+}
+
+func TestVkCategories(t *testing.T) {
+	oldCategories := config.VkCategories
+	t.Cleanup(func() { config.VkCategories = oldCategories })
+	config.VkCategories = map[int]entity.Category{1: {ID: 1605}, 2: {ID: 1605}}
+
+	got := vkCategories([]entity.Offer{{CategoryID: 1605}, {CategoryID: 1605}})
+	if len(got.Category) != 1 || got.Category[0].ID != 1605 || got.Category[0].Name != categoryName(1) {
+		t.Errorf("vkCategories() = %+v", got.Category)
+	}
+}
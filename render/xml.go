@@ -1,87 +1,138 @@
 package render
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
-	"encoding/xml"
 	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"sort"
+	"strconv"
 	"sync"
-	"time"
 	"yandex-export/config"
 	"yandex-export/entity"
-	"yandex-export/repository"
 )
 
 var currentVersion entity.Version
 var mu = &sync.Mutex{}
 
-// XmlHandler генерирует YML и отдаёт его в ответе
+// XmlHandler генерирует YML и отдаёт его в ответе. Предложения кодируются в ответ
+// по мере чтения из БД и не собираются в память целиком. Дата каталога, ETag и блок
+// currencies нужны до первого предложения, поэтому выборка читается дважды:
+// первый проход без картинок только считает версию (и при совпадении отвечает 304),
+// второй выбирает картинки и пишет предложения. Пулы картинок пересканируются
+// во втором проходе, так что замена фото попадает в версию со следующего запроса.
 func XmlHandler(w http.ResponseWriter, sr *http.Request) {
-	var passLink, classLink string
-	params := sr.URL.Query()
-	if len(params) > 0 {
-		passLink = params.Get("passlink")
-		classLink = params.Get("classlink")
-	}
-
-	if passLink != "" {
-		config.PassDefaultLink = passLink
-	}
+	compact := compactOutput(sr)
+	applyLinkParams(sr)
 
-	if classLink != "" {
-		config.ClassDefaultLink = classLink
-	}
-
-	log.Println("passLink:", passLink)
-	log.Println("classLink:", classLink)
-
-	classes, err := repository.FetchClasses()
+	var summary offerSummary
+	err := eachOffer(false, func(o entity.Offer) error {
+		summary.add(o)
+		return nil
+	})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("fetchClasses error: %v", err), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	passes, err := repository.FetchPasses()
-	if err != nil {
-		http.Error(w, fmt.Sprintf("fetchPasses error: %v", err), http.StatusInternalServerError)
+	version, ok := checkVersion(w, sr, summary.hash(picturesVersion()), xmlFormat("yml", compact))
+	if !ok {
 		return
 	}
 
-	offers := make([]entity.Offer, 0, len(classes)+len(passes))
-	offers = append(offers, classes...)
-	offers = append(offers, passes...)
+	catalog := ymlCatalog(version, config.Categories, nil)
+	catalog.Shop.Currencies = currenciesOf(summary.currencyIDs)
 
-	hash := HashOffers(offers)
-	mu.Lock()
-	if currentVersion.Hash != hash {
-		currentVersion.PubDate = time.Now().Format("2006-01-02T15:04-07:00")
-		currentVersion.Hash = hash
-		log.Println("Updating version: " + currentVersion.PubDate)
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	yw, err := newYmlWriter(w, catalog, !compact)
+	if err == nil {
+		// Если данные успели измениться между проходами, ответ окажется новее
+		// своего ETag, а версию обновит следующий запрос
+		err = eachOffer(true, yw.offer)
+	}
+	if err == nil {
+		err = yw.close()
+	}
+	if err != nil {
+		// Заголовки уже отправлены, остаётся только записать ошибку в лог
+		log.Printf("Ошибка записи YML: %v", err)
 	}
-	mu.Unlock()
+}
 
-	catalogWithDate := entity.YmlCatalog{
-		Name:    config.CompanyName,
-		Company: config.CompanyName,
-		Date:    currentVersion.PubDate,
+// ymlCatalog собирает каталог со стандартным заголовком магазина.
+// Блок currencies перечисляет только валюты, которые есть в предложениях.
+func ymlCatalog(version entity.Version, categories entity.Categories, offers []entity.Offer) entity.YmlCatalog {
+	return entity.YmlCatalog{
+		Date: version.PubDate,
 		Shop: entity.Shop{
-			Categories: config.Categories,
+			Name:       config.ShopName,
+			Company:    config.CompanyName,
+			URL:        config.ShopURL,
+			Platform:   config.ShopPlatform,
+			Version:    config.ShopVersion,
+			Email:      config.ShopEmail,
+			Currencies: ymlCurrencies(offers),
+			Categories: categories,
 			Offers:     entity.Offers{Offer: offers},
 		},
 	}
+}
 
-	outputWithDate, err := xml.MarshalIndent(catalogWithDate, "", "  ")
-	if err != nil {
-		http.Error(w, fmt.Sprintf("XML marshal error: %v", err), http.StatusInternalServerError)
-		return
+// ymlCurrencies перечисляет валюты предложений по алфавиту. Рубль — основная
+// валюта с курсом 1, остальные считаются по курсу ЦБ РФ. Без предложений блока нет.
+func ymlCurrencies(offers []entity.Offer) *entity.Currencies {
+	ids := make([]string, len(offers))
+	for i, o := range offers {
+		ids[i] = o.CurrencyID
 	}
+	return currenciesOf(ids)
+}
 
-	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
-	w.Write([]byte(xml.Header))
-	w.Write(outputWithDate)
+// currenciesOf строит блок currencies по кодам валют, пропуская пустые и повторы
+func currenciesOf(currencyIDs []string) *entity.Currencies {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, id := range currencyIDs {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	currencies := &entity.Currencies{}
+	for _, id := range ids {
+		rate := "CBRF"
+		if id == "RUR" || id == "RUB" {
+			rate = "1"
+		}
+		currencies.Currency = append(currencies.Currency, entity.Currency{ID: id, Rate: rate})
+	}
+	return currencies
+}
+
+// compactOutput сообщает, нужен ли XML без отступов: по умолчанию — config.XmlCompact,
+// в запросе можно переопределить параметром ?compact=1 или ?compact=0
+func compactOutput(sr *http.Request) bool {
+	if value := sr.URL.Query().Get("compact"); value != "" {
+		if compact, err := strconv.ParseBool(value); err == nil {
+			return compact
+		}
+	}
+	return config.XmlCompact
+}
+
+// xmlFormat различает ETag компактного и форматированного вывода
+func xmlFormat(format string, compact bool) string {
+	if compact {
+		return format + "-compact"
+	}
+	return format
 }
 
 func HashBytes(b []byte) string {
@@ -89,33 +140,77 @@ func HashBytes(b []byte) string {
 	return hex.EncodeToString(hash[:])
 }
 
-// HashOffers creates a hash based on the offers data from database
-func HashOffers(offers []entity.Offer) string {
-	// Sort offers by ID to ensure consistent hashing
-	sort.Slice(offers, func(i, j int) bool {
-		return offers[i].ID < offers[j].ID
-	})
+// HashOffers creates a hash based on the offers data from database.
+// picturesVersion is the image manager fingerprint: the pictures themselves
+// rotate on every request, so the set of available photos is hashed instead.
+// The order of offers does not matter.
+func HashOffers(offers []entity.Offer, picturesVersion string) string {
+	var summary offerSummary
+	for _, offer := range offers {
+		summary.add(offer)
+	}
+	return summary.hash(picturesVersion)
+}
 
+// offerSummary collects what the catalog header needs to know about the offers
+// without keeping the offers themselves: a digest per offer and the currencies
+type offerSummary struct {
+	digests     []offerDigest
+	currencyIDs []string
+}
+
+type offerDigest struct {
+	id     int
+	digest [sha256.Size]byte
+}
+
+func (s *offerSummary) add(offer entity.Offer) {
 	hasher := sha256.New()
+	// Include key fields that represent the data state
+	fmt.Fprintf(hasher, "%d|%s|%s|%s|%d|%d|%s|%s|",
+		offer.ID,
+		offer.Name,
+		offer.Description,
+		offer.Vendor,
+		offer.Price,
+		offer.CategoryID,
+		offer.CurrencyID,
+		offer.URL,
+	)
+
+	// Include ShortDescription if it exists
+	if offer.ShortDescription != "" {
+		fmt.Fprintf(hasher, "%s|", offer.ShortDescription)
+	}
 
-	for _, offer := range offers {
-		// Include key fields that represent the data state
-		fmt.Fprintf(hasher, "%d|%s|%s|%s|%d|%d|%s|%s|",
-			offer.ID,
-			offer.Name,
-			offer.Description,
-			offer.Vendor,
-			offer.Price,
-			offer.CategoryID,
-			offer.CurrencyID,
-			offer.URL,
-		)
-
-		// Include ShortDescription if it exists
-		if offer.ShortDescription != "" {
-			fmt.Fprintf(hasher, "%s|", offer.ShortDescription)
+	// Version changes when a group fills up or reopens
+	if !offer.IsAvailable() {
+		fmt.Fprint(hasher, "unavailable|")
+	}
+
+	digest := offerDigest{id: offer.ID}
+	hasher.Sum(digest.digest[:0])
+	s.digests = append(s.digests, digest)
+
+	if !slices.Contains(s.currencyIDs, offer.CurrencyID) {
+		s.currencyIDs = append(s.currencyIDs, offer.CurrencyID)
+	}
+}
+
+// hash combines the offer digests sorted by ID, so the order of offers does not matter
+func (s *offerSummary) hash(picturesVersion string) string {
+	slices.SortFunc(s.digests, func(a, b offerDigest) int {
+		if a.id != b.id {
+			return a.id - b.id
 		}
+		return bytes.Compare(a.digest[:], b.digest[:])
+	})
+
+	hasher := sha256.New()
+	for _, d := range s.digests {
+		hasher.Write(d.digest[:])
 	}
+	fmt.Fprintf(hasher, "pictures:%s|", picturesVersion)
 
 	return hex.EncodeToString(hasher.Sum(nil))
 }
@@ -0,0 +1,64 @@
+package render
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"yandex-export/entity"
+)
+
+func TestHashOffersAvailability(t *testing.T) {
+	available, full := true, false
+	offers := []entity.Offer{{ID: 1, Name: "Хип-хоп"}}
+
+	unknown := HashOffers(offers, "")
+	offers[0].Available = &available
+	if got := HashOffers(offers, ""); got != unknown {
+		t.Errorf("available offer changed the hash")
+	}
+	offers[0].Available = &full
+	if got := HashOffers(offers, ""); got == unknown {
+		t.Errorf("full group did not change the hash")
+	}
+}
+
+func TestHashOffersOrder(t *testing.T) {
+	// У класса и абонемента может быть одинаковый ID
+	offers := []entity.Offer{
+		{ID: 1, Kind: entity.OfferKindPass, Name: "Первое пробное занятие"},
+		{ID: 3, Kind: entity.OfferKindClass, Name: "Джаз-фанк"},
+		{ID: 1, Kind: entity.OfferKindClass, Name: "Хип-хоп"},
+	}
+	hash := HashOffers(offers, "pictures")
+
+	reordered := []entity.Offer{offers[2], offers[1], offers[0]}
+	if got := HashOffers(reordered, "pictures"); got != hash {
+		t.Errorf("order of offers changed the hash")
+	}
+	if offers[0].Name != "Первое пробное занятие" || reordered[0].Name != "Хип-хоп" {
+		t.Errorf("HashOffers reordered its input")
+	}
+	if got := HashOffers(offers, "other pictures"); got == hash {
+		t.Errorf("pictures version did not change the hash")
+	}
+}
+
+func TestOfferAvailableAttr(t *testing.T) {
+	full := false
+	tests := []struct {
+		available *bool
+		want      string
+	}{
+		{nil, `<offer id="1">`},
+		{&full, `<offer id="1" available="false">`},
+	}
+	for _, tt := range tests {
+		output, err := xml.Marshal(entity.Offer{ID: 1, Available: tt.available})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(output), tt.want) {
+			t.Errorf("got %s, want prefix %s", output, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package render
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+var update = flag.Bool("update", false, "перезаписать эталонные файлы в testdata")
+
+// TestYmlGolden сверяет структуру YML с эталоном testdata/yandex.golden.yml.
+// После намеренного изменения формата: go test ./render -run Golden -update
+func TestYmlGolden(t *testing.T) {
+	setShopConfig(t)
+
+	full := false
+	offers := []entity.Offer{
+		{
+			ID:               12,
+			Kind:             entity.OfferKindClass,
+			Vendor:           config.CompanyName,
+			Price:            700,
+			CurrencyID:       "RUR",
+			CategoryID:       1,
+			Pictures:         []string{"https://bezpravil.net/img/1/hip-hop.jpg"},
+			URL:              "https://bezpravil.net",
+			Name:             "Хип-хоп в студии Центр",
+			Description:      "Для начинающих\nПо понедельникам и средам в 19:00–20:30",
+			ShortDescription: "По понедельникам и средам в 19:00–20:30",
+			Params: []entity.Param{
+				{Name: "Понедельник", Value: "19:00–20:30"},
+				{Name: "Среда", Value: "19:00–20:30"},
+			},
+		},
+		{
+			ID:               13,
+			Kind:             entity.OfferKindClass,
+			Available:        &full,
+			Vendor:           config.CompanyName,
+			Price:            800,
+			CurrencyID:       "RUR",
+			CategoryID:       1,
+			URL:              "https://bezpravil.net",
+			Name:             "Джаз-фанк в студии Центр",
+			Description:      "По субботам в 12:00",
+			ShortDescription: "По субботам в 12:00",
+		},
+		{
+			ID:               1,
+			Kind:             entity.OfferKindPass,
+			Vendor:           config.CompanyName,
+			Price:            300,
+			CurrencyID:       "RUR",
+			CategoryID:       2,
+			Pictures:         []string{"https://bezpravil.net/img/2/first.jpg"},
+			URL:              "https://bezpravil.net",
+			Name:             "Первое пробное занятие",
+			Description:      "Первый урок в любом классе",
+			ShortDescription: "Первый урок в любом классе",
+		},
+	}
+	categories := entity.Categories{Category: []entity.Category{
+		{ID: 1, Name: "Танцевальные классы (разовое посещение)"},
+		{ID: 2, Name: "Абонементы"},
+	}}
+	catalog := ymlCatalog(entity.Version{PubDate: "2026-10-19T12:00+03:00"}, categories, offers)
+
+	var buf bytes.Buffer
+	if err := writeYml(&buf, catalog, true); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "yandex.golden.yml")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("YML differs from %s:\n%s", golden, buf.String())
+	}
+}
+
+func TestYmlWithoutOffers(t *testing.T) {
+	setShopConfig(t)
+
+	var buf bytes.Buffer
+	catalog := ymlCatalog(entity.Version{PubDate: "2026-10-19T12:00+03:00"}, entity.Categories{}, nil)
+	if err := writeYml(&buf, catalog, true); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("<currencies")) {
+		t.Errorf("Expected no currencies block without offers:\n%s", buf.String())
+	}
+}
+
+// setShopConfig задаёт заголовок магазина для тестов и возвращает прежний после теста
+func setShopConfig(t *testing.T) {
+	t.Helper()
+	oldName, oldCompany, oldURL := config.ShopName, config.CompanyName, config.ShopURL
+	oldPlatform, oldVersion, oldEmail := config.ShopPlatform, config.ShopVersion, config.ShopEmail
+	t.Cleanup(func() {
+		config.ShopName, config.CompanyName, config.ShopURL = oldName, oldCompany, oldURL
+		config.ShopPlatform, config.ShopVersion, config.ShopEmail = oldPlatform, oldVersion, oldEmail
+	})
+
+	config.ShopName = "Без правил"
+	config.CompanyName = "Школа танцев «Без правил»"
+	config.ShopURL = "https://bezpravil.net"
+	config.ShopPlatform = "yandex-export"
+	config.ShopVersion = "1.0"
+	config.ShopEmail = "hello@bezpravil.net"
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"yandex-export/config"
+)
+
+// fetchAvailability узнаёт, можно ли записаться в классы.
+// Источник задаётся config.AvailabilitySource: вместимость и число записанных
+// или флаг паузы. Возвращает доступность по class_id; nil, если источник не задан.
+// Классы, которых нет в результате, считаются доступными.
+func fetchAvailability() (map[int]bool, error) {
+	switch config.AvailabilitySource {
+	case "":
+		return nil, nil
+	case config.AvailabilitySourceCapacity:
+		return queryAvailability(config.CapacityQuery, func(rows *sql.Rows) (int, bool, error) {
+			var (
+				classID  int
+				capacity sql.NullInt64
+				enrolled int
+			)
+			if err := rows.Scan(&classID, &capacity, &enrolled); err != nil {
+				return 0, false, err
+			}
+			// Вместимость не задана — группа не бывает полной
+			return classID, !capacity.Valid || capacity.Int64 <= 0 || int64(enrolled) < capacity.Int64, nil
+		})
+	case config.AvailabilitySourcePaused:
+		return queryAvailability(config.PausedQuery, func(rows *sql.Rows) (int, bool, error) {
+			var (
+				classID int
+				paused  sql.NullBool
+			)
+			if err := rows.Scan(&classID, &paused); err != nil {
+				return 0, false, err
+			}
+			return classID, !paused.Bool, nil
+		})
+	default:
+		return nil, fmt.Errorf("fetchAvailability: unknown source %q", config.AvailabilitySource)
+	}
+}
+
+func queryAvailability(query string, scan func(rows *sql.Rows) (int, bool, error)) (map[int]bool, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("fetchAvailability: %w", err)
+	}
+	defer rows.Close()
+
+	availability := make(map[int]bool)
+	for rows.Next() {
+		classID, available, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		availability[classID] = available
+	}
+	return availability, rows.Err()
+}
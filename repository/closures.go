@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"yandex-export/common"
+	"yandex-export/config"
+	"yandex-export/entity"
+	"yandex-export/schedule"
+)
+
+// closureFileEntry — запись календаря закрытий в JSON-файле config.ClosuresFile:
+// [{"from": "2026-06-01", "to": "2026-08-31", "studio_id": 0, "reason": "Летние каникулы"}]
+type closureFileEntry struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	StudioID int    `json:"studio_id"`
+	Reason   string `json:"reason"`
+}
+
+// fetchClosures собирает календарь закрытий из файла и таблицы БД, если они заданы.
+// В таблице ожидаются колонки studio_id (NULL — вся школа), date_from, date_to и reason.
+func fetchClosures() ([]entity.Closure, error) {
+	var closures []entity.Closure
+
+	if config.ClosuresFile != "" {
+		data, err := os.ReadFile(config.ClosuresFile)
+		if err != nil {
+			return nil, fmt.Errorf("fetchClosures: %w", err)
+		}
+		var entries []closureFileEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("fetchClosures: %s: %w", config.ClosuresFile, err)
+		}
+		for _, e := range entries {
+			c, err := newClosure(e.From, e.To, e.StudioID, e.Reason)
+			if err != nil {
+				return nil, fmt.Errorf("fetchClosures: %s: %w", config.ClosuresFile, err)
+			}
+			closures = append(closures, c)
+		}
+	}
+
+	if config.ClosuresTable != "" {
+		query := fmt.Sprintf("SELECT studio_id, date_from, date_to, reason FROM `%s` WHERE date_to >= CURDATE()",
+			strings.ReplaceAll(config.ClosuresTable, "`", ""))
+		rows, err := db.Query(query)
+		if err != nil {
+			return nil, fmt.Errorf("fetchClosures: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				studioID sql.NullInt64
+				from     string
+				to       string
+				reason   sql.NullString
+			)
+			if err := rows.Scan(&studioID, &from, &to, &reason); err != nil {
+				return nil, err
+			}
+			c, err := newClosure(from, to, int(studioID.Int64), reason.String)
+			if err != nil {
+				return nil, fmt.Errorf("fetchClosures: %w", err)
+			}
+			closures = append(closures, c)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return closures, nil
+}
+
+func newClosure(from, to string, studioID int, reason string) (entity.Closure, error) {
+	fromDate, err := common.ParseDate(from, config.Location)
+	if err != nil {
+		return entity.Closure{}, fmt.Errorf("invalid closure start %q: %w", from, err)
+	}
+	toDate, err := common.ParseDate(to, config.Location)
+	if err != nil {
+		return entity.Closure{}, fmt.Errorf("invalid closure end %q: %w", to, err)
+	}
+	return entity.Closure{From: fromDate, To: toDate, StudioID: studioID, Reason: reason}, nil
+}
+
+// closureFor возвращает закрытие, на которое приходится занятие, если оно есть
+func closureFor(closures []entity.Closure, at time.Time, studioID int) (entity.Closure, bool) {
+	for _, c := range closures {
+		if c.Covers(at, studioID) {
+			return c, true
+		}
+	}
+	return entity.Closure{}, false
+}
+
+// nextSession ищет ближайшее занятие класса, не попадающее на закрытия, в течение года
+func nextSession(o entity.Offer, closures []entity.Closure, from time.Time) (time.Time, bool) {
+	to := from.AddDate(1, 0, 0)
+	if !o.EndDate.IsZero() && o.EndDate.AddDate(0, 0, 1).Before(to) {
+		to = o.EndDate.AddDate(0, 0, 1)
+	}
+	for _, at := range schedule.Occurrences(o.Schedule, from, to) {
+		if _, closed := closureFor(closures, at, o.Studio.ID); !closed {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// closureNote проверяет занятия класса в ближайшие config.ClosureLookaheadDays дней.
+// Возвращает пометку для описания, если часть занятий отменена, и visible == false,
+// если занятий в этом окне нет совсем и их нужно скрыть по config.ClosurePolicy.
+func closureNote(o entity.Offer, closures []entity.Closure, now time.Time) (note string, visible bool) {
+	if len(closures) == 0 || o.Schedule.IsEmpty() {
+		return "", true
+	}
+
+	from := now
+	if o.StartDate.After(from) {
+		from = o.StartDate
+	}
+	to := now.AddDate(0, 0, config.ClosureLookaheadDays)
+
+	var cancelled []entity.Closure
+	var actual []time.Time
+	for _, at := range schedule.Occurrences(o.Schedule, from, to) {
+		if !o.EndDate.IsZero() && !at.Before(o.EndDate.AddDate(0, 0, 1)) {
+			break
+		}
+		if c, closed := closureFor(closures, at, o.Studio.ID); closed {
+			cancelled = append(cancelled, c)
+		} else {
+			actual = append(actual, at)
+		}
+	}
+
+	if len(cancelled) == 0 {
+		return "", true
+	}
+	if len(actual) > 0 {
+		return "Ближайшее занятие — " + formatSession(actual[0]) + ".", true
+	}
+	if config.ClosurePolicy == config.ClosurePolicyHide {
+		return "", false
+	}
+
+	closure := cancelled[0]
+	note = "Занятия временно не проводятся"
+	if closure.Reason != "" {
+		note += " (" + closure.Reason + ")"
+	}
+	note += "."
+	if next, ok := nextSession(o, closures, to); ok {
+		note += " Ближайшее занятие — " + formatSession(next) + "."
+	}
+	return note, true
+}
+
+func formatSession(at time.Time) string {
+	return common.FormatDate(at) + " в " + at.Format("15:04")
+}
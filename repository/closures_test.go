@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+func TestClosureNote(t *testing.T) {
+	loc := config.Location
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, loc) }
+
+	// Класс по понедельникам и средам в 19:00 в студии 3
+	offer := entity.Offer{
+		Studio: entity.Studio{ID: 3},
+		Schedule: entity.Schedule{Sessions: []entity.Session{
+			{Weekday: entity.Monday, Start: 19 * 60},
+			{Weekday: entity.Wednesday, Start: 19 * 60},
+		}},
+	}
+	// Понедельник, 6 июля 2026
+	now := time.Date(2026, time.July, 6, 10, 0, 0, 0, loc)
+
+	oldPolicy, oldDays := config.ClosurePolicy, config.ClosureLookaheadDays
+	defer func() { config.ClosurePolicy, config.ClosureLookaheadDays = oldPolicy, oldDays }()
+	config.ClosureLookaheadDays = 14
+
+	tests := []struct {
+		name        string
+		policy      string
+		closures    []entity.Closure
+		wantVisible bool
+		wantNote    string
+	}{
+		{"no closures", config.ClosurePolicyHide, nil, true, ""},
+		{"other studio", config.ClosurePolicyHide,
+			[]entity.Closure{{From: date(2026, 7, 1), To: date(2026, 8, 31), StudioID: 5}}, true, ""},
+		{"one day off", config.ClosurePolicyHide,
+			[]entity.Closure{{From: date(2026, 7, 6), To: date(2026, 7, 6)}}, true, "Ближайшее занятие — 8 июля в 19:00."},
+		{"summer break hidden", config.ClosurePolicyHide,
+			[]entity.Closure{{From: date(2026, 7, 1), To: date(2026, 8, 31), StudioID: 3}}, false, ""},
+		{"summer break annotated", config.ClosurePolicyAnnotate,
+			[]entity.Closure{{From: date(2026, 7, 1), To: date(2026, 8, 31), Reason: "Летние каникулы"}}, true,
+			"Занятия временно не проводятся (Летние каникулы). Ближайшее занятие — 2 сентября в 19:00."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.ClosurePolicy = tt.policy
+			note, visible := closureNote(offer, tt.closures, now)
+			if visible != tt.wantVisible {
+				t.Errorf("visible = %v, want %v", visible, tt.wantVisible)
+			}
+			if note != tt.wantNote {
+				t.Errorf("note = %q, want %q", note, tt.wantNote)
+			}
+		})
+	}
+
+	t.Run("class ends during break", func(t *testing.T) {
+		config.ClosurePolicy = config.ClosurePolicyAnnotate
+		ending := offer
+		ending.EndDate = date(2026, 8, 15)
+		note, visible := closureNote(ending, []entity.Closure{{From: date(2026, 7, 1), To: date(2026, 8, 31)}}, now)
+		if !visible || strings.Contains(note, "Ближайшее") {
+			t.Errorf("unexpected note %q (visible %v)", note, visible)
+		}
+	})
+}
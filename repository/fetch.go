@@ -5,12 +5,12 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strings"
 	"time"
 	"yandex-export/common"
 	"yandex-export/config"
 	"yandex-export/entity"
 	"yandex-export/images"
+	"yandex-export/schedule"
 )
 
 var db *sql.DB
@@ -39,58 +39,125 @@ func InitDB() (*sql.DB, error) {
 	}
 	log.Println("Подключились к БД")
 
+	if err := detectStyleTitleColumn(); err != nil {
+		log.Printf("Не удалось определить колонку с названием стиля: %v", err)
+	}
+
 	return db, err
 }
 
 // FetchClasses тянет из БД текущие записи из classes
 func FetchClasses() ([]entity.Offer, error) {
+	var list []entity.Offer
+	err := EachClass(true, func(o entity.Offer) error {
+		list = append(list, o)
+		return nil
+	})
+	return list, err
+}
+
+// EachClass передаёт fn классы из classes по одному, по мере чтения выборки,
+// не собирая их в список. С pictures == false картинки не выбираются: так
+// можно посчитать версию каталога, не сдвигая ротацию картинок. Ошибка fn прерывает чтение.
+func EachClass(pictures bool, fn func(entity.Offer) error) error {
 	query := `
 		SELECT
   c.id,
   c.string       AS name,
   c.description  AS class_description,
-  (
-    SELECT st.description
-    FROM styles_classes AS sc
-    JOIN styles          AS st ON st.id = sc.style_id
-    WHERE sc.class_id = c.id
-    ORDER BY sc.id DESC, st.id DESC
-    LIMIT 1
-  ) AS style_description,
+  st.description  AS style_description,
+  st.id,
+  %s AS style_title,
   c.mon, c.tue, c.wed, c.thu, c.fri, c.sat, c.sun,
+  s.id,
   s.studio_title,
-		c.price_rate
+		c.price_rate,
+  c.start_date,
+  c.end_date
 FROM classes AS c
 JOIN studios AS s
   ON c.studio_id = s.id
+LEFT JOIN styles AS st
+  ON st.id = (
+    SELECT sst.id
+    FROM styles_classes AS sc
+    JOIN styles          AS sst ON sst.id = sc.style_id
+    WHERE sc.class_id = c.id
+    ORDER BY sc.id DESC, sst.id DESC
+    LIMIT 1
+  )
 WHERE c.hidden   IS NULL
   AND c.deleted  IS NULL
   AND c.string   IS NOT NULL
-  AND (c.start_date IS NULL OR c.start_date <= NOW())
-  AND (c.end_date   IS NULL OR c.end_date   >= NOW());
+  AND (c.start_date IS NULL OR c.start_date <= NOW() + INTERVAL ? DAY)
+  AND (c.end_date   IS NULL OR c.end_date   >= NOW())
+ORDER BY c.id;
     `
 
-	rows, err := db.Query(query)
+	var extras classExtras
+	var err error
+	if extras.sessions, err = fetchSessions(); err != nil {
+		return err
+	}
+	if extras.closures, err = fetchClosures(); err != nil {
+		return err
+	}
+	if extras.teachers, err = fetchTeachers(); err != nil {
+		return err
+	}
+	if extras.availability, err = fetchAvailability(); err != nil {
+		return err
+	}
+	extras.now = time.Now().In(config.Location)
+
+	rows, err := db.Query(fmt.Sprintf(query, styleTitleExpr), config.UpcomingDays)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var list []entity.Offer
+	var keys []OfferImageKey
 	for rows.Next() {
-		o, err := scanClass(rows)
+		o, hidden, err := scanClass(rows, &extras)
 		if err != nil {
-			return list, err
+			return err
+		}
+		if hidden {
+			continue
+		}
+		if pictures {
+			key := classImageKey(o)
+			o.Pictures = getImages(key)
+			keys = append(keys, offerImageKey(o, key))
+		}
+		if err := fn(o); err != nil {
+			return err
 		}
-		list = append(list, o)
 	}
-	return list, rows.Err()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if pictures {
+		rememberImageKeys(&classImageKeys, keys)
+	}
+	return nil
 }
 
 // FetchPasses тянет из БД текущие записи из passes
 func FetchPasses() ([]entity.Offer, error) {
+	var list []entity.Offer
+	err := EachPass(true, func(o entity.Offer) error {
+		list = append(list, o)
+		return nil
+	})
+	return list, err
+}
+
+// EachPass передаёт fn абонементы по одному, как EachClass — классы
+func EachPass(pictures bool, fn func(entity.Offer) error) error {
 	query := `
-		SELECT t.ticket_type_name                      AS name,
+		SELECT t.id,
+			   t.ticket_type_name                      AS name,
 			   t.description,
 			   t.default_price                         AS price,
 			   t.default_period                        AS lifetime,
@@ -104,55 +171,103 @@ func FetchPasses() ([]entity.Offer, error) {
 
 	rows, err := db.Query(query)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var list []entity.Offer = []entity.Offer{
+	if !pictures {
+		return eachPass(rows, fn)
+	}
+	var keys []OfferImageKey
+	err = eachPass(rows, func(o entity.Offer) error {
+		key := passImageKey(o.ID)
+		o.Pictures = getImages(key)
+		keys = append(keys, offerImageKey(o, key))
+		return fn(o)
+	})
+	if err != nil {
+		return err
+	}
+	rememberImageKeys(&passImageKeys, keys)
+	return nil
+}
+
+// rowScanner — то, что нужно от *sql.Rows при разборе выборки
+type rowScanner interface {
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+}
+
+// passIDOffset сдвигает ID абонементов из ticket_types: 1 и 2 заняты
+// пробным и разовым занятием, которых нет в таблице
+const passIDOffset = 2
+
+// eachPass передаёт fn пробное и разовое занятие, а затем абонементы из выборки.
+// ID абонемента выводится из ticket_types.id, поэтому не меняется,
+// когда абонементы добавляют, удаляют или меняют им цену.
+func eachPass(rows rowScanner, fn func(entity.Offer) error) error {
+	static := []entity.Offer{
 		{
 			ID:          1,
+			Kind:        entity.OfferKindPass,
 			Name:        "Первое пробное занятие",
 			Description: "Первый урок в любом классе",
 			Vendor:      config.CompanyName,
 			Price:       config.FirstVisitPrice,
 			CurrencyID:  "RUR",
 			CategoryID:  2,
-			Picture:     config.PassDefaultPicture,
 			URL:         config.PassDefaultLink,
 		},
 		{
 			ID:          2,
+			Kind:        entity.OfferKindPass,
 			Name:        "Разовое занятие",
 			Description: "Одно часовое посещение в любом классе",
 			Vendor:      config.CompanyName,
 			Price:       config.VisitPrice,
 			CurrencyID:  "RUR",
 			CategoryID:  2,
-			Picture:     config.PassDefaultPicture,
 			URL:         config.PassDefaultLink,
 		},
 	}
-	var id int = 3
+	for _, o := range static {
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
 	for rows.Next() {
-		id++
-		o, empty, err := scanPass(rows, id)
+		o, empty, err := scanPass(rows)
 		if err != nil {
-			return list, err
+			return err
 		}
 		if empty {
 			continue
 		}
-		list = append(list, o)
+		if err := fn(o); err != nil {
+			return err
+		}
 	}
-	return list, rows.Err()
+	return rows.Err()
 }
 
-func scanClass(rows *sql.Rows) (entity.Offer, error) {
+// classExtras — данные, загруженные отдельно от основного запроса классов
+type classExtras struct {
+	sessions     map[int][]entity.Session
+	closures     []entity.Closure
+	teachers     map[int][]entity.Teacher
+	availability map[int]bool
+	now          time.Time
+}
+
+func scanClass(rows *sql.Rows, extras *classExtras) (entity.Offer, bool, error) {
 	var (
 		o         entity.Offer
 		name      string
 		classDesc sql.NullString
 		styleDesc sql.NullString
+		styleID   sql.NullInt64
+		style     sql.NullString
 		mon       sql.NullString
 		tue       sql.NullString
 		wed       sql.NullString
@@ -160,29 +275,80 @@ func scanClass(rows *sql.Rows) (entity.Offer, error) {
 		fri       sql.NullString
 		sat       sql.NullString
 		sun       sql.NullString
+		studioID  int
 		studio    sql.NullString
 		price     sql.NullInt64
+		startDate sql.NullString
+		endDate   sql.NullString
 	)
 	if err := rows.Scan(
-		&o.ID, &name, &classDesc, &styleDesc, &mon, &tue, &wed, &thu, &fri, &sat, &sun, &studio, &price,
+		&o.ID, &name, &classDesc, &styleDesc, &styleID, &style, &mon, &tue, &wed, &thu, &fri, &sat, &sun,
+		&studioID, &studio, &price, &startDate, &endDate,
 	); err != nil {
-		return entity.Offer{}, err
+		return entity.Offer{}, false, err
 	}
 
+	o.Kind = entity.OfferKindClass
+	o.Studio = entity.Studio{ID: studioID, Title: studio.String}
+	if styleID.Valid {
+		o.Style = entity.Style{ID: int(styleID.Int64), Title: style.String, Slug: common.Slugify(style.String)}
+	}
 	if studio.Valid {
 		name += " в студии " + studio.String
 	}
+	if startDate.Valid {
+		if t, err := common.ParseDate(startDate.String, config.Location); err == nil {
+			o.StartDate = t
+		}
+	}
+	if endDate.Valid {
+		if t, err := common.ParseDate(endDate.String, config.Location); err == nil {
+			o.EndDate = t
+		}
+	}
+
+	nameSuffix, startParams, categoryID := newGroup(o.StartDate, extras.now)
+	name += nameSuffix
 
 	var description string
-	schedule := getSchedule(mon, tue, wed, thu, fri, sat, sun)
+	if classSessions := extras.sessions[o.ID]; len(classSessions) > 0 {
+		o.Schedule = entity.Schedule{Sessions: classSessions}
+	} else {
+		o.Schedule = parseSchedule(o.ID, mon, tue, wed, thu, fri, sat, sun)
+	}
+	o.Params = schedule.Params(o.Schedule)
+	o.Params = append(o.Params, startParams...)
+	o.Teachers = extras.teachers[o.ID]
+	o.Params = append(o.Params, teacherParams(o.Teachers)...)
+	scheduleText := schedule.Text(o.Schedule)
 	if classDesc.Valid && classDesc.String != "" {
 		description = classDesc.String + "\n"
 	} else if styleDesc.Valid && styleDesc.String != "" {
 		description = styleDesc.String + "\n"
 	}
+	if teachers := teachersText(o.Teachers); teachers != "" {
+		description += teachers + "\n"
+	}
 
-	fullDescription := description + schedule
-	shortDescription := common.SafelyTruncate(schedule, 250)
+	if extras.availability != nil {
+		available, known := extras.availability[o.ID]
+		available = available || !known
+		if !available && config.AvailabilityPolicy == config.AvailabilityPolicyHide {
+			return o, true, nil
+		}
+		o.Available = &available
+	}
+
+	note, visible := closureNote(o, extras.closures, extras.now)
+	if !visible {
+		return o, true, nil
+	}
+	if note != "" {
+		scheduleText += "\n" + note
+	}
+
+	fullDescription := description + scheduleText
+	shortDescription := common.SafelyTruncate(scheduleText, 250)
 
 	o.Name = common.SafelyTruncate(name, 250)
 	o.Vendor = config.CompanyName
@@ -197,21 +363,34 @@ func scanClass(rows *sql.Rows) (entity.Offer, error) {
 	} else {
 		o.Price = config.VisitPrice
 	}
-	// Try to get a random image for category 1, fallback to default if no images available
-	if randomImage, err := imageManager.GetRandomImage(1); err == nil {
-		o.Picture = randomImage
-	} else {
-		o.Picture = config.ClassDefaultPicture
-	}
 	o.URL = config.ClassDefaultLink
 	o.CurrencyID = "RUR"
-	o.CategoryID = 1
-	return o, nil
+	o.CategoryID = categoryID
+	return o, false, nil
 }
 
-func scanPass(rows *sql.Rows, id int) (entity.Offer, bool, error) {
+// newGroup описывает группу, которая ещё не стартовала и попала в выгрузку
+// благодаря config.UpcomingDays: суффикс названия «— старт 2 сентября»,
+// параметр «Дата старта» и категорию config.NewGroupsCategoryID, если она задана.
+// Для уже идущих групп возвращает пустой суффикс и категорию 1.
+func newGroup(start, now time.Time) (string, []entity.Param, int) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if !start.After(today) {
+		return "", nil, 1
+	}
+
+	categoryID := 1
+	if config.NewGroupsCategoryID > 0 {
+		categoryID = config.NewGroupsCategoryID
+	}
+	params := []entity.Param{{Name: "Дата старта", Value: start.Format("2006-01-02")}}
+	return " — старт " + common.FormatDate(start), params, categoryID
+}
+
+func scanPass(rows rowScanner) (entity.Offer, bool, error) {
 	var (
 		o              entity.Offer
+		ticketTypeID   int
 		name           string
 		desc           sql.NullString
 		price          sql.NullInt64
@@ -221,7 +400,7 @@ func scanPass(rows *sql.Rows, id int) (entity.Offer, bool, error) {
 		guest_visits   sql.NullInt64
 	)
 	if err := rows.Scan(
-		&name, &desc, &price,
+		&ticketTypeID, &name, &desc, &price,
 		&lifetime, &hours, &freeze_allowed, &guest_visits,
 	); err != nil {
 		return entity.Offer{}, false, err
@@ -261,7 +440,8 @@ func scanPass(rows *sql.Rows, id int) (entity.Offer, bool, error) {
 	fullDescription := desc.String + lifetimeString + lessonsIncluded + freezeAllowed
 	shortDescription := common.SafelyTruncate(desc.String, 250)
 
-	o.ID = id
+	o.ID = ticketTypeID + passIDOffset
+	o.Kind = entity.OfferKindPass
 	o.Name = common.SafelyTruncate(name, 250)
 	o.Description = fullDescription
 	if len(fullDescription) > 250 {
@@ -271,76 +451,8 @@ func scanPass(rows *sql.Rows, id int) (entity.Offer, bool, error) {
 	}
 	o.Vendor = config.CompanyName
 	o.Price = int(price.Int64)
-	o.Picture = config.PassDefaultPicture
 	o.URL = config.PassDefaultLink
 	o.CurrencyID = "RUR"
 	o.CategoryID = 2
 	return o, false, nil
 }
-
-func getSchedule(mon sql.NullString, tue sql.NullString, wed sql.NullString, thu sql.NullString, fri sql.NullString, sat sql.NullString, sun sql.NullString) string {
-	days := map[int]string{
-		0: "понедельникам",
-		1: "вторникам",
-		2: "средам",
-		3: "четвергам",
-		4: "пятницам",
-		5: "субботам",
-		6: "воскресеньям",
-	}
-
-	schedules := make(map[string][]string)
-	for dayIndex, timeValue := range []sql.NullString{mon, tue, wed, thu, fri, sat, sun} {
-		if timeValue.Valid {
-			day := days[dayIndex]
-			timeString := timeValue.String
-			if time, err := time.Parse("15:04:04", timeString); err == nil {
-				timeString := time.Format("15:04")
-				if schedules[timeString] == nil {
-					schedules[timeString] = make([]string, 0)
-				}
-				schedules[timeString] = append(schedules[timeString], day)
-			}
-		}
-	}
-
-	scheduleStrings := make([]string, 0, len(schedules))
-	for time, days := range schedules {
-		str := "По "
-		var lastDay string
-		if len(days) > 1 {
-			lastDay = days[len(days)-1]
-			days = days[:len(days)-1]
-			str += strings.Join(days, ", ") + " и " + lastDay
-		} else {
-			str += days[0]
-		}
-		str += " в " + time
-
-		scheduleStrings = append(scheduleStrings, str)
-	}
-
-	return strings.Join(scheduleStrings, "; ")
-}
-
-// getRandomImageForCategory returns a random image for the given category ID
-// Falls back to default picture if no images are available
-func getRandomImageForCategory(categoryID int) string {
-	if imageManager == nil {
-		// Fallback to default if image manager is not initialized
-		if categoryID == 1 {
-			return config.ClassDefaultPicture
-		}
-		return config.PassDefaultPicture
-	}
-
-	if randomImage, err := imageManager.GetRandomImage(categoryID); err == nil {
-		return randomImage
-	}
-
-	// Fallback to default picture
-	if categoryID == 1 {
-		return config.ClassDefaultPicture
-	}
-	return config.PassDefaultPicture
-}
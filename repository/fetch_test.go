@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+func TestNewGroup(t *testing.T) {
+	loc := config.Location
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, loc) }
+	// Понедельник, 31 августа 2026, вечер
+	now := time.Date(2026, time.August, 31, 21, 0, 0, 0, loc)
+
+	oldCategory := config.NewGroupsCategoryID
+	defer func() { config.NewGroupsCategoryID = oldCategory }()
+
+	tests := []struct {
+		name         string
+		start        time.Time
+		newGroupsID  int
+		wantSuffix   string
+		wantStart    string
+		wantCategory int
+	}{
+		{"no start date", time.Time{}, 3, "", "", 1},
+		{"started long ago", date(2026, 1, 12), 3, "", "", 1},
+		{"starts today", date(2026, 8, 31), 3, "", "", 1},
+		{"starts tomorrow", date(2026, 9, 1), 3, " — старт 1 сентября", "2026-09-01", 3},
+		{"starts in two weeks", date(2026, 9, 14), 3, " — старт 14 сентября", "2026-09-14", 3},
+		{"no new groups category", date(2026, 9, 2), 0, " — старт 2 сентября", "2026-09-02", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.NewGroupsCategoryID = tt.newGroupsID
+			suffix, params, categoryID := newGroup(tt.start, now)
+			if suffix != tt.wantSuffix {
+				t.Errorf("suffix = %q, want %q", suffix, tt.wantSuffix)
+			}
+			if categoryID != tt.wantCategory {
+				t.Errorf("categoryID = %d, want %d", categoryID, tt.wantCategory)
+			}
+			var gotStart string
+			for _, p := range params {
+				if p.Name == "Дата старта" {
+					gotStart = p.Value
+				}
+			}
+			if gotStart != tt.wantStart {
+				t.Errorf("«Дата старта» = %q, want %q", gotStart, tt.wantStart)
+			}
+		})
+	}
+}
+
+func TestClassImageKey(t *testing.T) {
+	class := entity.Offer{ID: 7, Style: entity.Style{Slug: "hip-hop"}, Studio: entity.Studio{ID: 3}, CategoryID: 1}
+	if got, want := classImageKey(class).String(), "class/7, style/hip-hop, studio/3, 1"; got != want {
+		t.Errorf("regular class key = %q, want %q", got, want)
+	}
+
+	// Новая группа ищет картинки класса, затем свою категорию и только потом общую
+	class.CategoryID = 5
+	if got, want := classImageKey(class).String(), "class/7, style/hip-hop, studio/3, 5, 1"; got != want {
+		t.Errorf("new group key = %q, want %q", got, want)
+	}
+
+	// Фото преподавателей важнее стиля и студии
+	class.CategoryID = 1
+	class.Teachers = []entity.Teacher{{ID: 4, Name: "Анна"}, {ID: 9, Name: "Олег"}}
+	if got, want := classImageKey(class).String(), "class/7, teacher/4, teacher/9, style/hip-hop, studio/3, 1"; got != want {
+		t.Errorf("class with teachers key = %q, want %q", got, want)
+	}
+}
+
+// fakeRows отдаёт заранее заданные строки выборки абонементов
+type fakeRows struct {
+	rows [][]any
+	i    int
+}
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.i <= len(r.rows)
+}
+
+func (r *fakeRows) Err() error { return nil }
+
+func (r *fakeRows) Scan(dest ...any) error {
+	for i, value := range r.rows[r.i-1] {
+		switch d := dest[i].(type) {
+		case *int:
+			*d = value.(int)
+		case *string:
+			*d = value.(string)
+		case *sql.NullString:
+			*d = sql.NullString{String: value.(string), Valid: true}
+		case *sql.NullInt64:
+			*d = sql.NullInt64{Int64: int64(value.(int)), Valid: true}
+		default:
+			return fmt.Errorf("unsupported destination %T", dest[i])
+		}
+	}
+	return nil
+}
+
+func TestEachPassStableIDs(t *testing.T) {
+	// id, name, description, price, lifetime, hours, freeze_allowed, guest_visits
+	month := []any{7, "Месяц", "Восемь занятий", 5000, 30, 8, 1, 0}
+	quarter := []any{3, "Квартал", "Двадцать четыре занятия", 13000, 90, 24, 1, 2}
+	single := []any{12, "Безлимит", "Все классы", 9000, 30, 0, 0, 0}
+
+	idsByName := func(rows ...[]any) map[string]int {
+		t.Helper()
+		var offers []entity.Offer
+		err := eachPass(&fakeRows{rows: rows}, func(o entity.Offer) error {
+			offers = append(offers, o)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("eachPass failed: %v", err)
+		}
+		ids := make(map[string]int)
+		for _, o := range offers {
+			if _, dup := ids[o.Name]; dup {
+				t.Fatalf("duplicate pass %q", o.Name)
+			}
+			ids[o.Name] = o.ID
+		}
+		return ids
+	}
+
+	byPrice := idsByName(month, single, quarter)
+	reordered := idsByName(quarter, month)
+
+	if byPrice["Первое пробное занятие"] != 1 || byPrice["Разовое занятие"] != 2 {
+		t.Errorf("static passes must keep IDs 1 and 2, got %v", byPrice)
+	}
+	for _, name := range []string{"Месяц", "Квартал"} {
+		if byPrice[name] != reordered[name] {
+			t.Errorf("%s: ID changed from %d to %d when the query order changed", name, byPrice[name], reordered[name])
+		}
+	}
+	if byPrice["Месяц"] != 7+passIDOffset {
+		t.Errorf("Месяц: ID = %d, want %d", byPrice["Месяц"], 7+passIDOffset)
+	}
+	for name, id := range byPrice {
+		if id <= 2 && name != "Первое пробное занятие" && name != "Разовое занятие" {
+			t.Errorf("%s: ID %d collides with a static pass", name, id)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"log"
+	"sync"
+	"yandex-export/config"
+	"yandex-export/entity"
+	"yandex-export/images"
+)
+
+// OfferImageKey связывает предложение с ключом, по которому для него выбираются картинки
+type OfferImageKey struct {
+	OfferID    int        `json:"offer_id"`
+	Name       string     `json:"name"`
+	CategoryID int        `json:"category_id"`
+	Key        images.Key `json:"key"`
+}
+
+var imageKeysMu sync.Mutex
+var classImageKeys []OfferImageKey
+var passImageKeys []OfferImageKey
+
+// Images возвращает менеджер картинок, который используется при выгрузке
+func Images() *images.ImageManager {
+	return imageManager
+}
+
+// ImageKeys возвращает ключи картинок предложений из последней выгрузки
+func ImageKeys() []OfferImageKey {
+	imageKeysMu.Lock()
+	defer imageKeysMu.Unlock()
+
+	keys := make([]OfferImageKey, 0, len(classImageKeys)+len(passImageKeys))
+	keys = append(keys, classImageKeys...)
+	keys = append(keys, passImageKeys...)
+	return keys
+}
+
+func offerImageKey(o entity.Offer, key images.Key) OfferImageKey {
+	return OfferImageKey{OfferID: o.ID, Name: o.Name, CategoryID: o.CategoryID, Key: key}
+}
+
+func rememberImageKeys(target *[]OfferImageKey, keys []OfferImageKey) {
+	imageKeysMu.Lock()
+	defer imageKeysMu.Unlock()
+	*target = keys
+}
+
+// getImages возвращает до config.MaxPictures картинок по цепочке пулов ключа
+// (см. images.Key), а если менеджер картинок не инициализирован или сломался — key.Fallback
+func getImages(key images.Key) []string {
+	if imageManager == nil {
+		return []string{key.Fallback}
+	}
+	images, err := imageManager.GetRandomImages(key, config.MaxPictures)
+	if err != nil {
+		log.Printf("Не удалось выбрать картинки (%s): %v", key, err)
+		return []string{key.Fallback}
+	}
+	return images
+}
+
+// classImageKey — классы ищут картинки от самых частных пулов к категории класса:
+// фото преподавателей идут сразу после картинок самого класса.
+// Новые группы сначала смотрят в свою категорию, затем в общую категорию классов.
+func classImageKey(o entity.Offer) images.Key {
+	key := images.Key{
+		OfferID:    o.ID,
+		ClassID:    o.ID,
+		TeacherIDs: teacherIDs(o.Teachers),
+		StyleSlug:  o.Style.Slug,
+		StudioID:   o.Studio.ID,
+		CategoryID: 1,
+		Fallback:   config.ClassDefaultPicture,
+	}
+	if o.CategoryID > 0 && o.CategoryID != 1 {
+		key.CategoryID = o.CategoryID
+		key.ParentCategoryID = 1
+	}
+	return key
+}
+
+// passImageKey — абонементы берут картинки из своей категории
+func passImageKey(offerID int) images.Key {
+	return images.Key{OfferID: offerID, CategoryID: 2, Fallback: config.PassDefaultPicture}
+}
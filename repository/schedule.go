@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+// parseSchedule собирает расписание из колонок mon..sun таблицы classes
+func parseSchedule(classID int, mon sql.NullString, tue sql.NullString, wed sql.NullString, thu sql.NullString, fri sql.NullString, sat sql.NullString, sun sql.NullString) entity.Schedule {
+	var s entity.Schedule
+	for dayIndex, timeValue := range []sql.NullString{mon, tue, wed, thu, fri, sat, sun} {
+		if !timeValue.Valid || strings.TrimSpace(timeValue.String) == "" {
+			continue
+		}
+		start, err := parseClock(timeValue.String)
+		if err != nil {
+			log.Printf("Качество данных: класс %d, %s: не удалось разобрать время %q: %v",
+				classID, entity.Weekday(dayIndex), timeValue.String, err)
+			continue
+		}
+		s.Sessions = append(s.Sessions, entity.Session{
+			Weekday: entity.Weekday(dayIndex),
+			Start:   start,
+		})
+	}
+	return s
+}
+
+// fetchSessions тянет занятия из необязательной таблицы config.ClassSessionsTable.
+// В таблице ожидаются колонки class_id, weekday (1 — понедельник, …, 7 — воскресенье),
+// start и end (может быть NULL). Возвращает занятия, сгруппированные по class_id.
+func fetchSessions() (map[int][]entity.Session, error) {
+	if config.ClassSessionsTable == "" {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf("SELECT class_id, weekday, `start`, `end` FROM `%s` ORDER BY class_id, weekday, `start`",
+		strings.ReplaceAll(config.ClassSessionsTable, "`", ""))
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("fetchSessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make(map[int][]entity.Session)
+	for rows.Next() {
+		var (
+			classID int
+			weekday int
+			start   sql.NullString
+			end     sql.NullString
+		)
+		if err := rows.Scan(&classID, &weekday, &start, &end); err != nil {
+			return nil, err
+		}
+
+		if weekday < 1 || weekday > 7 {
+			log.Printf("Качество данных: класс %d: неизвестный день недели %d", classID, weekday)
+			continue
+		}
+		if !start.Valid {
+			log.Printf("Качество данных: класс %d, %s: не указано время начала", classID, entity.Weekday(weekday-1))
+			continue
+		}
+
+		session := entity.Session{Weekday: entity.Weekday(weekday - 1)}
+		if session.Start, err = parseClock(start.String); err != nil {
+			log.Printf("Качество данных: класс %d, %s: не удалось разобрать время начала %q: %v",
+				classID, session.Weekday, start.String, err)
+			continue
+		}
+		if end.Valid && end.String != "" {
+			if endTime, err := parseClock(end.String); err != nil {
+				log.Printf("Качество данных: класс %d, %s: не удалось разобрать время окончания %q: %v",
+					classID, session.Weekday, end.String, err)
+			} else if endTime > session.Start {
+				session.Duration = time.Duration(endTime-session.Start) * time.Minute
+			} else {
+				log.Printf("Качество данных: класс %d, %s: время окончания %s не позже начала %s",
+					classID, session.Weekday, endTime, session.Start)
+			}
+		}
+
+		sessions[classID] = append(sessions[classID], session)
+	}
+	return sessions, rows.Err()
+}
+
+// parseClock разбирает время в форматах MySQL TIME «15:04:05» и «15:04»
+func parseClock(value string) (entity.TimeOfDay, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return entity.TimeOfDay(t.Hour()*60 + t.Minute()), nil
+		}
+	}
+	return 0, fmt.Errorf("expected HH:MM or HH:MM:SS")
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+// FetchStudios тянет студии с адресами запросом config.StudiosQuery
+func FetchStudios() ([]entity.Studio, error) {
+	rows, err := db.Query(config.StudiosQuery)
+	if err != nil {
+		return nil, fmt.Errorf("fetchStudios: %w", err)
+	}
+	defer rows.Close()
+
+	var studios []entity.Studio
+	for rows.Next() {
+		var (
+			s       entity.Studio
+			title   sql.NullString
+			address sql.NullString
+		)
+		if err := rows.Scan(&s.ID, &title, &address); err != nil {
+			return nil, err
+		}
+		s.Title = strings.TrimSpace(title.String)
+		s.Address = strings.TrimSpace(address.String)
+		studios = append(studios, s)
+	}
+	return studios, rows.Err()
+}
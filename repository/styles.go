@@ -0,0 +1,36 @@
+package repository
+
+import "log"
+
+// styleTitleColumns — колонки таблицы styles, в которых может лежать название стиля,
+// в порядке предпочтения. Схема CRM различается между инсталляциями.
+var styleTitleColumns = []string{"style_title", "title", "name"}
+
+// styleTitleExpr подставляется в запрос классов вместо названия стиля.
+// Пока колонка не найдена, стиль выгружается без названия: картинки стиля
+// не ищутся, остальные данные класса не страдают.
+var styleTitleExpr = "NULL"
+
+// detectStyleTitleColumn проверяет по information_schema, какая из styleTitleColumns
+// есть в таблице styles
+func detectStyleTitleColumn() error {
+	for _, column := range styleTitleColumns {
+		var count int
+		err := db.QueryRow(`
+			SELECT COUNT(*)
+			FROM information_schema.columns
+			WHERE table_schema = DATABASE()
+			  AND table_name = 'styles'
+			  AND column_name = ?`, column).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			styleTitleExpr = "st." + column
+			log.Printf("Название стиля берём из styles.%s", column)
+			return nil
+		}
+	}
+	log.Printf("В таблице styles нет ни одной из колонок %v, выгружаем стили без названий", styleTitleColumns)
+	return nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"yandex-export/config"
+	"yandex-export/entity"
+)
+
+// fetchTeachers тянет преподавателей классов, если это включено в config.TeachersEnabled.
+// Возвращает преподавателей, сгруппированных по class_id.
+func fetchTeachers() (map[int][]entity.Teacher, error) {
+	if !config.TeachersEnabled {
+		return nil, nil
+	}
+
+	rows, err := db.Query(config.TeachersQuery)
+	if err != nil {
+		return nil, fmt.Errorf("fetchTeachers: %w", err)
+	}
+	defer rows.Close()
+
+	teachers := make(map[int][]entity.Teacher)
+	for rows.Next() {
+		var classID int
+		var t entity.Teacher
+		if err := rows.Scan(&classID, &t.ID, &t.Name); err != nil {
+			return nil, err
+		}
+		t.Name = strings.TrimSpace(t.Name)
+		if t.Name == "" {
+			continue
+		}
+		teachers[classID] = append(teachers[classID], t)
+	}
+	return teachers, rows.Err()
+}
+
+// teachersText возвращает строку «Преподаватель: …» для описания класса
+func teachersText(teachers []entity.Teacher) string {
+	if len(teachers) == 0 {
+		return ""
+	}
+	names := make([]string, len(teachers))
+	for i, t := range teachers {
+		names[i] = t.Name
+	}
+	if len(names) == 1 {
+		return "Преподаватель: " + names[0]
+	}
+	return "Преподаватели: " + strings.Join(names, ", ")
+}
+
+// teacherParams возвращает по YML-параметру на каждого преподавателя
+func teacherParams(teachers []entity.Teacher) []entity.Param {
+	var params []entity.Param
+	for _, t := range teachers {
+		params = append(params, entity.Param{Name: "Преподаватель", Value: t.Name})
+	}
+	return params
+}
+
+func teacherIDs(teachers []entity.Teacher) []int {
+	ids := make([]int, len(teachers))
+	for i, t := range teachers {
+		ids[i] = t.ID
+	}
+	return ids
+}
@@ -0,0 +1,34 @@
+package schedule
+
+import (
+	"sort"
+	"time"
+	"yandex-export/entity"
+)
+
+// Occurrences возвращает начала всех занятий расписания в интервале [from, to),
+// по возрастанию. Время считается в часовом поясе from.
+func Occurrences(s entity.Schedule, from, to time.Time) []time.Time {
+	var result []time.Time
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		weekday := WeekdayOf(day)
+		for _, session := range s.Sessions {
+			if session.Weekday != weekday {
+				continue
+			}
+			start := time.Date(day.Year(), day.Month(), day.Day(),
+				int(session.Start)/60, int(session.Start)%60, 0, 0, day.Location())
+			if !start.Before(from) && start.Before(to) {
+				result = append(result, start)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+	return result
+}
+
+// WeekdayOf переводит день недели time.Time в entity.Weekday
+func WeekdayOf(t time.Time) entity.Weekday {
+	return entity.Weekday((int(t.Weekday()) + 6) % 7)
+}
@@ -0,0 +1,41 @@
+package schedule
+
+import (
+	"sort"
+	"strings"
+	"yandex-export/entity"
+)
+
+var nominative = [...]string{
+	entity.Monday:    "Понедельник",
+	entity.Tuesday:   "Вторник",
+	entity.Wednesday: "Среда",
+	entity.Thursday:  "Четверг",
+	entity.Friday:    "Пятница",
+	entity.Saturday:  "Суббота",
+	entity.Sunday:    "Воскресенье",
+}
+
+// Params возвращает расписание в виде YML-параметров: по одному на день недели,
+// например <param name="Понедельник">19:00–20:30</param>
+func Params(s entity.Schedule) []entity.Param {
+	sessions := append([]entity.Session(nil), s.Sessions...)
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].Start < sessions[j].Start })
+
+	var times [entity.Sunday + 1][]string
+	for _, session := range sessions {
+		times[session.Weekday] = append(times[session.Weekday], session.TimeRange())
+	}
+
+	var params []entity.Param
+	for day, list := range times {
+		if len(list) == 0 {
+			continue
+		}
+		params = append(params, entity.Param{
+			Name:  nominative[day],
+			Value: strings.Join(list, ", "),
+		})
+	}
+	return params
+}
@@ -0,0 +1,191 @@
+// Package schedule превращает entity.Schedule в текст и параметры для выгрузок
+package schedule
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"yandex-export/entity"
+)
+
+var dative = [...]string{
+	entity.Monday:    "понедельникам",
+	entity.Tuesday:   "вторникам",
+	entity.Wednesday: "средам",
+	entity.Thursday:  "четвергам",
+	entity.Friday:    "пятницам",
+	entity.Saturday:  "субботам",
+	entity.Sunday:    "воскресеньям",
+}
+
+var short = [...]string{
+	entity.Monday:    "пн",
+	entity.Tuesday:   "вт",
+	entity.Wednesday: "ср",
+	entity.Thursday:  "чт",
+	entity.Friday:    "пт",
+	entity.Saturday:  "сб",
+	entity.Sunday:    "вс",
+}
+
+// dayMask — множество дней недели, бит i соответствует entity.Weekday(i)
+type dayMask uint8
+
+const (
+	everyDay dayMask = 1<<7 - 1
+	workdays dayMask = 1<<5 - 1
+	weekends dayMask = 1<<entity.Saturday | 1<<entity.Sunday
+)
+
+func (m dayMask) has(d entity.Weekday) bool {
+	return m&(1<<d) != 0
+}
+
+func (m dayMask) first() entity.Weekday {
+	for d := entity.Monday; d <= entity.Sunday; d++ {
+		if m.has(d) {
+			return d
+		}
+	}
+	return entity.Sunday + 1
+}
+
+// slot — время занятия без привязки к дню недели
+type slot struct {
+	start    entity.TimeOfDay
+	duration time.Duration
+}
+
+func (s slot) less(other slot) bool {
+	if s.start != other.start {
+		return s.start < other.start
+	}
+	return s.duration < other.duration
+}
+
+func (s slot) String() string {
+	return entity.Session{Start: s.start, Duration: s.duration}.TimeRange()
+}
+
+// group — одно или несколько времён занятий, общих для набора дней
+type group struct {
+	days  dayMask
+	slots []slot
+}
+
+// Text возвращает расписание в виде фраз вроде «По будням в 19:00; По субботам в 12:00».
+// Группы упорядочены по первому дню недели, затем по времени начала,
+// поэтому результат не зависит от порядка занятий в расписании.
+func Text(s entity.Schedule) string {
+	// Сначала собираем дни для каждого времени занятия...
+	bySlot := make(map[slot]dayMask)
+	for _, session := range s.Sessions {
+		bySlot[slot{session.Start, session.Duration}] |= 1 << session.Weekday
+	}
+
+	// ...затем объединяем времена с одинаковым набором дней
+	byDays := make(map[dayMask]*group)
+	var groups []*group
+	for sl, days := range bySlot {
+		g, ok := byDays[days]
+		if !ok {
+			g = &group{days: days}
+			byDays[days] = g
+			groups = append(groups, g)
+		}
+		g.slots = append(g.slots, sl)
+	}
+
+	for _, g := range groups {
+		sort.Slice(g.slots, func(i, j int) bool { return g.slots[i].less(g.slots[j]) })
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if fi, fj := groups[i].days.first(), groups[j].days.first(); fi != fj {
+			return fi < fj
+		}
+		return groups[i].slots[0].less(groups[j].slots[0])
+	})
+
+	scheduleStrings := make([]string, 0, len(groups))
+	for _, g := range groups {
+		times := make([]string, len(g.slots))
+		for i, sl := range g.slots {
+			times[i] = sl.String()
+		}
+		str := capitalize(daysPhrase(g.days)) + " в " + joinAnd(times)
+		scheduleStrings = append(scheduleStrings, str)
+	}
+
+	return strings.Join(scheduleStrings, "; ")
+}
+
+// daysPhrase описывает набор дней: «ежедневно», «по будням», «по выходным»,
+// «пн–чт, сб», если есть отрезок хотя бы из трёх дней подряд,
+// и «по понедельникам и средам» в остальных случаях
+func daysPhrase(days dayMask) string {
+	switch days {
+	case everyDay:
+		return "ежедневно"
+	case workdays:
+		return "по будням"
+	case weekends:
+		return "по выходным"
+	}
+
+	type run struct{ from, to entity.Weekday }
+	var runs []run
+	hasLongRun := false
+	for d := entity.Monday; d <= entity.Sunday; d++ {
+		if !days.has(d) {
+			continue
+		}
+		if len(runs) > 0 && runs[len(runs)-1].to == d-1 {
+			runs[len(runs)-1].to = d
+		} else {
+			runs = append(runs, run{d, d})
+		}
+		if r := runs[len(runs)-1]; r.to-r.from >= 2 {
+			hasLongRun = true
+		}
+	}
+
+	if hasLongRun {
+		parts := make([]string, 0, len(runs))
+		for _, r := range runs {
+			switch {
+			case r.to-r.from >= 2:
+				parts = append(parts, short[r.from]+"–"+short[r.to])
+			case r.to > r.from:
+				parts = append(parts, short[r.from], short[r.to])
+			default:
+				parts = append(parts, short[r.from])
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	var names []string
+	for d := entity.Monday; d <= entity.Sunday; d++ {
+		if days.has(d) {
+			names = append(names, dative[d])
+		}
+	}
+	return "по " + joinAnd(names)
+}
+
+// joinAnd соединяет элементы как «a, b и c»
+func joinAnd(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " и " + items[len(items)-1]
+}
+
+func capitalize(s string) string {
+	for i := range s {
+		if i > 0 {
+			return strings.ToUpper(s[:i]) + s[i:]
+		}
+	}
+	return strings.ToUpper(s)
+}
@@ -0,0 +1,111 @@
+package schedule
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+	"yandex-export/entity"
+)
+
+func at(day entity.Weekday, hh, mm int) entity.Session {
+	return entity.Session{Weekday: day, Start: entity.TimeOfDay(hh*60 + mm)}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name     string
+		sessions []entity.Session
+		want     string
+	}{
+		{"empty", nil, ""},
+		{"single day", []entity.Session{at(entity.Monday, 19, 0)}, "По понедельникам в 19:00"},
+		{"two days", []entity.Session{at(entity.Monday, 19, 0), at(entity.Wednesday, 19, 0)}, "По понедельникам и средам в 19:00"},
+		{"three separate days", []entity.Session{at(entity.Monday, 19, 0), at(entity.Wednesday, 19, 0), at(entity.Friday, 19, 0)}, "По понедельникам, средам и пятницам в 19:00"},
+		{"adjacent pair", []entity.Session{at(entity.Tuesday, 18, 30), at(entity.Wednesday, 18, 30)}, "По вторникам и средам в 18:30"},
+		{"workdays", []entity.Session{at(entity.Monday, 19, 0), at(entity.Tuesday, 19, 0), at(entity.Wednesday, 19, 0), at(entity.Thursday, 19, 0), at(entity.Friday, 19, 0)}, "По будням в 19:00"},
+		{"weekends", []entity.Session{at(entity.Saturday, 12, 0), at(entity.Sunday, 12, 0)}, "По выходным в 12:00"},
+		{"every day", []entity.Session{at(entity.Monday, 9, 0), at(entity.Tuesday, 9, 0), at(entity.Wednesday, 9, 0), at(entity.Thursday, 9, 0), at(entity.Friday, 9, 0), at(entity.Saturday, 9, 0), at(entity.Sunday, 9, 0)}, "Ежедневно в 09:00"},
+		{"range", []entity.Session{at(entity.Monday, 19, 0), at(entity.Tuesday, 19, 0), at(entity.Wednesday, 19, 0), at(entity.Thursday, 19, 0)}, "Пн–чт в 19:00"},
+		{"range and single day", []entity.Session{at(entity.Monday, 19, 0), at(entity.Tuesday, 19, 0), at(entity.Wednesday, 19, 0), at(entity.Friday, 19, 0)}, "Пн–ср, пт в 19:00"},
+		{"range and pair", []entity.Session{at(entity.Tuesday, 19, 0), at(entity.Wednesday, 19, 0), at(entity.Thursday, 19, 0), at(entity.Saturday, 19, 0), at(entity.Sunday, 19, 0)}, "Вт–чт, сб, вс в 19:00"},
+		{"different times", []entity.Session{at(entity.Wednesday, 20, 0), at(entity.Monday, 19, 0)}, "По понедельникам в 19:00; По средам в 20:00"},
+		{"same days, several times", []entity.Session{at(entity.Monday, 21, 0), at(entity.Monday, 19, 0)}, "По понедельникам в 19:00 и 21:00"},
+		{"workdays and weekends", []entity.Session{at(entity.Saturday, 12, 0), at(entity.Sunday, 12, 0), at(entity.Monday, 19, 0), at(entity.Tuesday, 19, 0), at(entity.Wednesday, 19, 0), at(entity.Thursday, 19, 0), at(entity.Friday, 19, 0)}, "По будням в 19:00; По выходным в 12:00"},
+		{"with duration", []entity.Session{
+			{Weekday: entity.Tuesday, Start: 19 * 60, Duration: 90 * time.Minute},
+			{Weekday: entity.Thursday, Start: 19 * 60, Duration: 90 * time.Minute},
+		}, "По вторникам и четвергам в 19:00–20:30"},
+		{"two sessions a day with duration", []entity.Session{
+			{Weekday: entity.Saturday, Start: 14 * 60, Duration: time.Hour},
+			{Weekday: entity.Saturday, Start: 11 * 60, Duration: time.Hour},
+		}, "По субботам в 11:00–12:00 и 14:00–15:00"},
+		{"same first day ordered by time", []entity.Session{at(entity.Monday, 20, 0), at(entity.Tuesday, 20, 0), at(entity.Monday, 10, 0)}, "По понедельникам в 10:00; По понедельникам и вторникам в 20:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Text(entity.Schedule{Sessions: tt.sessions})
+			if got != tt.want {
+				t.Errorf("Text() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestText_AllDayCombinations проверяет все 127 непустых наборов дней:
+// текст не зависит от порядка занятий и упоминает каждый день ровно один раз
+func TestText_AllDayCombinations(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for mask := dayMask(1); mask <= everyDay; mask++ {
+		var sessions []entity.Session
+		for d := entity.Monday; d <= entity.Sunday; d++ {
+			if mask.has(d) {
+				sessions = append(sessions, at(d, 19, 0))
+			}
+		}
+
+		want := Text(entity.Schedule{Sessions: sessions})
+		if !strings.HasSuffix(want, " в 19:00") || strings.Contains(want, ";") {
+			t.Errorf("mask %07b: unexpected text %q", mask, want)
+		}
+		if phrase := daysPhrase(mask); phrase != "ежедневно" && phrase != "по будням" && phrase != "по выходным" {
+			for d := entity.Monday; d <= entity.Sunday; d++ {
+				mentioned := strings.Contains(phrase, dative[d]) || strings.Contains(phrase, short[d])
+				if mentioned != mask.has(d) && !inRange(phrase, d) {
+					t.Errorf("mask %07b: day %s mentioned=%v in %q", mask, d, mentioned, phrase)
+				}
+			}
+		}
+
+		for i := 0; i < 5; i++ {
+			shuffled := append([]entity.Session(nil), sessions...)
+			r.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+			if got := Text(entity.Schedule{Sessions: shuffled}); got != want {
+				t.Fatalf("mask %07b: Text depends on order: %q != %q", mask, got, want)
+			}
+		}
+	}
+}
+
+// inRange сообщает, покрыт ли день отрезком вида «пн–чт» внутри фразы
+func inRange(phrase string, d entity.Weekday) bool {
+	for _, part := range strings.Split(phrase, ", ") {
+		if from, to, ok := strings.Cut(part, "–"); ok {
+			var fromDay, toDay entity.Weekday
+			for i, s := range short {
+				if s == from {
+					fromDay = entity.Weekday(i)
+				}
+				if s == to {
+					toDay = entity.Weekday(i)
+				}
+			}
+			if d >= fromDay && d <= toDay {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"yandex-export/config"
+	"yandex-export/images"
+	"yandex-export/repository"
+)
+
+// registerAdminHandlers регистрирует ручки управления картинками:
+//
+//	GET  <prefix>/images          — картинки по пулам и результат их проверки
+//	GET  <prefix>/images/usage    — счётчики показов
+//	POST <prefix>/images/reset    — сбросить счётчики
+//	POST <prefix>/images/rescan   — пересканировать пул ?pool=1 (или class/12, style/jazz…)
+//	GET  <prefix>/images/preview  — какие картинки сейчас получило бы каждое предложение
+func registerAdminHandlers(prefix string) {
+	http.HandleFunc(prefix+"/images", adminOnly(http.MethodGet, adminImagesHandler))
+	http.HandleFunc(prefix+"/images/usage", adminOnly(http.MethodGet, adminUsageHandler))
+	http.HandleFunc(prefix+"/images/reset", adminOnly(http.MethodPost, adminResetHandler))
+	http.HandleFunc(prefix+"/images/rescan", adminOnly(http.MethodPost, adminRescanHandler))
+	http.HandleFunc(prefix+"/images/preview", adminOnly(http.MethodGet, adminPreviewHandler))
+}
+
+// adminOnly проверяет метод и токен (Authorization: Bearer <ADMIN_TOKEN>)
+// и что менеджер картинок уже инициализирован
+func adminOnly(method string, next func(http.ResponseWriter, *http.Request, *images.ImageManager)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(config.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		imageManager := repository.Images()
+		if imageManager == nil {
+			http.Error(w, "image manager is not initialized", http.StatusServiceUnavailable)
+			return
+		}
+		next(w, r, imageManager)
+	}
+}
+
+func adminImagesHandler(w http.ResponseWriter, r *http.Request, imageManager *images.ImageManager) {
+	writeJSON(w, imageManager.GetPools())
+}
+
+func adminUsageHandler(w http.ResponseWriter, r *http.Request, imageManager *images.ImageManager) {
+	writeJSON(w, imageManager.GetUsageStats())
+}
+
+func adminResetHandler(w http.ResponseWriter, r *http.Request, imageManager *images.ImageManager) {
+	imageManager.ResetUsageStats()
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+func adminRescanHandler(w http.ResponseWriter, r *http.Request, imageManager *images.ImageManager) {
+	pool := r.URL.Query().Get("pool")
+	if pool == "" {
+		http.Error(w, "pool parameter is required", http.StatusBadRequest)
+		return
+	}
+	if err := imageManager.Rescan(pool); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, imageManager.GetPools()[pool])
+}
+
+type imagePreview struct {
+	repository.OfferImageKey
+	Pictures []string `json:"pictures"`
+	Error    string   `json:"error,omitempty"`
+}
+
+func adminPreviewHandler(w http.ResponseWriter, r *http.Request, imageManager *images.ImageManager) {
+	keys := repository.ImageKeys()
+	if len(keys) == 0 {
+		http.Error(w, "the feed has not been generated yet", http.StatusConflict)
+		return
+	}
+
+	previews := make([]imagePreview, 0, len(keys))
+	for _, key := range keys {
+		preview := imagePreview{OfferImageKey: key}
+		pictures, err := imageManager.PreviewImages(key.Key, config.MaxPictures)
+		if err != nil {
+			preview.Error = err.Error()
+		}
+		preview.Pictures = pictures
+		previews = append(previews, preview)
+	}
+	writeJSON(w, previews)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
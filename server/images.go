@@ -0,0 +1,21 @@
+package server
+
+import (
+	"net/http"
+	"yandex-export/images"
+)
+
+type imageDiagnostics struct {
+	Rejected []images.Rejection `json:"rejected"`
+}
+
+// imageDiagnosticsHandler показывает картинки, отброшенные при последнем сканировании.
+// Ответ содержит пути на диске, поэтому ручка доступна только с ADMIN_TOKEN.
+func imageDiagnosticsHandler(w http.ResponseWriter, r *http.Request, imageManager *images.ImageManager) {
+	diagnostics := imageDiagnostics{Rejected: imageManager.GetRejections()}
+	if diagnostics.Rejected == nil {
+		diagnostics.Rejected = []images.Rejection{}
+	}
+
+	writeJSON(w, diagnostics)
+}
@@ -11,6 +11,40 @@ import (
 
 func InitAndRun() {
 	http.HandleFunc(config.YandexPath, render.XmlHandler)
+	if config.GooglePath != "" {
+		http.HandleFunc(config.GooglePath, render.GoogleHandler)
+	}
+	if config.VkPath != "" {
+		http.HandleFunc(config.VkPath, render.VkHandler)
+	}
+	if config.JsonPath != "" {
+		http.HandleFunc(config.JsonPath, render.JsonHandler)
+	}
+	if config.CsvPath != "" {
+		http.HandleFunc(config.CsvPath, render.CsvHandler)
+	}
+	if config.JsonLdPath != "" {
+		http.HandleFunc(config.JsonLdPath, render.JsonLdHandler)
+	}
+	if config.ICalPath != "" {
+		http.HandleFunc(config.ICalPath, render.ICalHandler)
+	}
+	if config.BusinessPath != "" {
+		http.HandleFunc(config.BusinessPath, render.BusinessHandler)
+	}
+	if config.AvitoPath != "" {
+		http.HandleFunc(config.AvitoPath, render.AvitoHandler)
+	}
+	if config.AdminToken != "" {
+		registerAdminHandlers(config.AdminPath)
+		if config.ImageDiagnosticsPath != "" {
+			http.HandleFunc(config.ImageDiagnosticsPath, adminOnly(http.MethodGet, imageDiagnosticsHandler))
+		}
+	}
+	if config.ServeImages {
+		http.HandleFunc(config.ImageServePath, staticImagesHandler)
+		log.Printf("Раздаём картинки по адресу %s\n", config.ImageServePath)
+	}
 	port := ":" + config.Port
 	log.Printf("Слушаем порт %s\n", port)
 
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"yandex-export/config"
+	"yandex-export/repository"
+)
+
+// hashedPrefix — первый сегмент адреса оригинала: 16 символов хеша содержимого
+var hashedPrefix = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+const (
+	immutableCache  = "public, max-age=31536000, immutable"
+	revalidateCache = "public, max-age=300"
+)
+
+// contentHash возвращает хеш файла, посчитанный менеджером картинок при сканировании
+var contentHash = func(filePath string) (string, bool) {
+	imageManager := repository.Images()
+	if imageManager == nil {
+		return "", false
+	}
+	return imageManager.ContentHash(filePath)
+}
+
+// staticImagesHandler раздаёт картинки по адресам, которые строит images.ImageManager
+// при включённом config.ServeImages:
+//
+//	<IMAGE_SERVE_PATH>/<hash16>/<путь в IMAGE_DIR> — оригинал
+//	<IMAGE_SERVE_PATH>/cache/<имя>                 — уменьшенная копия из IMAGE_CACHE_DIR
+func staticImagesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rel := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(r.URL.Path, config.ImageServePath)), "/")
+	first, rest, _ := strings.Cut(rel, "/")
+
+	var filePath, cacheControl string
+	switch {
+	case first == "cache" && rest != "" && !strings.Contains(rest, "/"):
+		// Имя уменьшенной копии уже содержит хеш оригинала
+		filePath = filepath.Join(config.ImageCacheDir, rest)
+		cacheControl = immutableCache
+	case hashedPrefix.MatchString(first) && rest != "":
+		filePath = filepath.Join(config.ImageDir, filepath.FromSlash(rest))
+		// Надолго кешируем, только если хеш из последнего сканирования совпадает с адресом.
+		// Файл заменили под тем же именем или его ещё не сканировали — отдаём содержимое,
+		// но не даём его закешировать надолго.
+		cacheControl = revalidateCache
+		if hash, ok := contentHash(filePath); ok && strings.HasPrefix(hash, first) {
+			cacheControl = immutableCache
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(filePath)))
+	if !strings.HasPrefix(contentType, "image/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", cacheControl)
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), f)
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"yandex-export/config"
+	"yandex-export/images"
+)
+
+func writePNG(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewGray(image.Rect(0, 0, 300, 300))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStaticImagesHandler(t *testing.T) {
+	root := t.TempDir()
+	imageDir := filepath.Join(root, "images")
+	cacheDir := filepath.Join(root, "cache")
+
+	oldImageDir, oldCacheDir, oldServePath := config.ImageDir, config.ImageCacheDir, config.ImageServePath
+	oldContentHash := contentHash
+	t.Cleanup(func() {
+		config.ImageDir, config.ImageCacheDir, config.ImageServePath = oldImageDir, oldCacheDir, oldServePath
+		contentHash = oldContentHash
+	})
+	config.ImageDir, config.ImageCacheDir, config.ImageServePath = imageDir, cacheDir, "/img/"
+
+	writePNG(t, filepath.Join(imageDir, "1", "photo.png"))
+	writePNG(t, filepath.Join(cacheDir, "0123456789abcdef-600x600.png"))
+	writePNG(t, filepath.Join(root, "secret.png"))
+	if err := os.WriteFile(filepath.Join(imageDir, "1", "notes.txt"), []byte("text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Хеши берутся из сканирования менеджера картинок, а не считаются на каждый запрос
+	imageManager := images.NewImageManager()
+	if _, err := imageManager.GetRandomImage(images.CategoryKey(1)); err != nil {
+		t.Fatalf("GetRandomImage failed: %v", err)
+	}
+	contentHash = imageManager.ContentHash
+	hash, ok := imageManager.ContentHash(filepath.Join(imageDir, "1", "photo.png"))
+	if !ok {
+		t.Fatal("Expected the scanned photo to have a content hash")
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		wantStatus   int
+		wantCache    string
+		wantMimeType string
+	}{
+		{"original", http.MethodGet, "/img/" + hash[:16] + "/1/photo.png", http.StatusOK, immutableCache, "image/png"},
+		{"head", http.MethodHead, "/img/" + hash[:16] + "/1/photo.png", http.StatusOK, immutableCache, "image/png"},
+		{"replaced file", http.MethodGet, "/img/ffffffffffffffff/1/photo.png", http.StatusOK, revalidateCache, "image/png"},
+		{"derivative", http.MethodGet, "/img/cache/0123456789abcdef-600x600.png", http.StatusOK, immutableCache, "image/png"},
+		{"missing file", http.MethodGet, "/img/" + hash[:16] + "/1/missing.png", http.StatusNotFound, "", ""},
+		{"not an image", http.MethodGet, "/img/" + hash[:16] + "/1/notes.txt", http.StatusNotFound, "", ""},
+		{"no hash", http.MethodGet, "/img/1/photo.png", http.StatusNotFound, "", ""},
+		{"traversal", http.MethodGet, "/img/" + hash[:16] + "/../../secret.png", http.StatusNotFound, "", ""},
+		{"traversal from cache", http.MethodGet, "/img/cache/../../secret.png", http.StatusNotFound, "", ""},
+		{"escaped traversal", http.MethodGet, "/img/cache/..%2f..%2fsecret.png", http.StatusNotFound, "", ""},
+		{"post", http.MethodPost, "/img/" + hash[:16] + "/1/photo.png", http.StatusMethodNotAllowed, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			staticImagesHandler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Cache-Control"); tt.wantCache != "" && got != tt.wantCache {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCache)
+			}
+			if got := rec.Header().Get("Content-Type"); tt.wantMimeType != "" && got != tt.wantMimeType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantMimeType)
+			}
+		})
+	}
+}